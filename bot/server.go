@@ -10,6 +10,7 @@ import (
 
 	"github.com/argoproj/notifications-engine/pkg/subscriptions"
 
+	"github.com/argoproj-labs/argocd-notifications/shared/healthz"
 	"github.com/argoproj-labs/argocd-notifications/shared/k8s"
 
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -20,20 +21,26 @@ import (
 type Server interface {
 	Serve(port int) error
 	AddAdapter(path string, adapter Adapter)
+	AddReadinessCheck(name string, check healthz.Checker)
 }
 
 func NewServer(dynamicClient dynamic.Interface, namespace string) *server {
-	return &server{
-		mux:           http.NewServeMux(),
-		appClient:     k8s.NewAppClient(dynamicClient, namespace),
-		appProjClient: k8s.NewAppProjClient(dynamicClient, namespace),
+	s := &server{
+		mux:             http.NewServeMux(),
+		appClient:       k8s.NewAppClient(dynamicClient, namespace),
+		appProjClient:   k8s.NewAppProjClient(dynamicClient, namespace),
+		readinessChecks: map[string]healthz.Checker{},
 	}
+	s.mux.HandleFunc("/healthz", healthz.LivenessHandler)
+	s.mux.Handle("/readyz", healthz.NewReadinessHandler(s.readinessChecks))
+	return s
 }
 
 type server struct {
-	appClient     dynamic.ResourceInterface
-	appProjClient dynamic.ResourceInterface
-	mux           *http.ServeMux
+	appClient       dynamic.ResourceInterface
+	appProjClient   dynamic.ResourceInterface
+	mux             *http.ServeMux
+	readinessChecks map[string]healthz.Checker
 }
 
 func copyStringMap(in map[string]string) map[string]string {
@@ -171,6 +178,11 @@ func (s *server) AddAdapter(pattern string, adapter Adapter) {
 	s.mux.HandleFunc(pattern, s.handler(adapter))
 }
 
+// AddReadinessCheck adds a check to the /readyz endpoint. Checks must be added before the server is started.
+func (s *server) AddReadinessCheck(name string, check healthz.Checker) {
+	s.readinessChecks[name] = check
+}
+
 func (s *server) Serve(port int) error {
 	return http.ListenAndServe(fmt.Sprintf(":%d", port), s.mux)
 }
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 
 	"github.com/argoproj/notifications-engine/pkg/api"
@@ -8,10 +10,12 @@ import (
 	"github.com/spf13/cobra"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
 
 	"github.com/argoproj-labs/argocd-notifications/bot"
 	"github.com/argoproj-labs/argocd-notifications/bot/slack"
+	"github.com/argoproj-labs/argocd-notifications/shared/debug"
 	"github.com/argoproj-labs/argocd-notifications/shared/k8s"
 	"github.com/argoproj-labs/argocd-notifications/shared/settings"
 )
@@ -22,6 +26,7 @@ func newBotCommand() *cobra.Command {
 		namespace    string
 		port         int
 		slackPath    string
+		debugPort    int
 	)
 	var command = cobra.Command{
 		Use:   "bot",
@@ -46,12 +51,28 @@ func newBotCommand() *cobra.Command {
 				}
 			}
 
-			apiFactory := api.NewFactory(settings.GetFactorySettings(nil),
-				namespace,
-				k8s.NewSecretInformer(clientset, namespace), k8s.NewConfigMapInformer(clientset, namespace))
+			secretInformer := k8s.NewSecretInformer(clientset, namespace)
+			configMapInformer := k8s.NewConfigMapInformer(clientset, namespace)
+			apiFactory := api.NewFactory(settings.GetFactorySettings(nil, nil), namespace, secretInformer, configMapInformer)
+			go secretInformer.Run(context.Background().Done())
+			go configMapInformer.Run(context.Background().Done())
+
+			debug.Serve(debugPort, debug.NewState(c.Flags(), namespace, func() map[string]cache.SharedIndexInformer {
+				return map[string]cache.SharedIndexInformer{"secrets": secretInformer, "configmaps": configMapInformer}
+			}))
 
 			server := bot.NewServer(dynamicClient, namespace)
 			server.AddAdapter(fmt.Sprintf("/%s", slackPath), slack.NewSlackAdapter(slack.NewVerifier(apiFactory)))
+			server.AddReadinessCheck("caches", func() error {
+				if !secretInformer.HasSynced() || !configMapInformer.HasSynced() {
+					return errors.New("caches are not synced")
+				}
+				return nil
+			})
+			server.AddReadinessCheck("config", func() error {
+				_, err := apiFactory.GetAPI()
+				return err
+			})
 			return server.Serve(port)
 		},
 	}
@@ -59,5 +80,6 @@ func newBotCommand() *cobra.Command {
 	command.Flags().IntVar(&port, "port", 8080, "Port number.")
 	command.Flags().StringVar(&namespace, "namespace", "", "Namespace which bot handles. Current namespace if empty.")
 	command.Flags().StringVar(&slackPath, "slack-path", "slack", "Path to the slack bot handler")
+	command.Flags().IntVar(&debugPort, "debug-port", 0, "Port of the debug server which serves pprof profiles and the runtime state. The debug server is disabled if zero.")
 	return &command
 }
@@ -2,28 +2,60 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/argoproj-labs/argocd-notifications/controller"
+	"github.com/argoproj-labs/argocd-notifications/controller/deadletter"
+	"github.com/argoproj-labs/argocd-notifications/controller/digest"
+	"github.com/argoproj-labs/argocd-notifications/controller/history"
+	"github.com/argoproj-labs/argocd-notifications/controller/state"
 	"github.com/argoproj-labs/argocd-notifications/shared/argocd"
+	"github.com/argoproj-labs/argocd-notifications/shared/debug"
+	"github.com/argoproj-labs/argocd-notifications/shared/healthz"
 	"github.com/argoproj-labs/argocd-notifications/shared/k8s"
+	"github.com/argoproj-labs/argocd-notifications/shared/tracing"
 
 	notificationscontroller "github.com/argoproj/notifications-engine/pkg/controller"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/client-go/tools/record"
 )
 
 const (
-	defaultMetricsPort = 9001
+	defaultMetricsPort     = 9001
+	defaultLeaseName       = "argocd-notifications-controller"
+	defaultLeaseDuration   = 15 * time.Second
+	defaultRenewDeadline   = 10 * time.Second
+	defaultLeaseRetryTime  = 2 * time.Second
+	defaultShutdownTimeout = 20 * time.Second
+	defaultHistoryCMName   = "argocd-notifications-history"
+	historyPersistInterval = 30 * time.Second
+	defaultDeadLetterSize  = 100
+	stateStoreAnnotation   = "annotation"
+	stateStoreConfigMap    = "configmap"
+	tracingShutdownTimeout = 5 * time.Second
+	defaultArgoCDCacheTTL  = time.Minute
+	defaultArgoCDCacheSize = 1000
 )
 
 func newControllerCommand() *cobra.Command {
@@ -38,8 +70,35 @@ func newControllerCommand() *cobra.Command {
 		argocdRepoServer          string
 		argocdRepoServerPlaintext bool
 		argocdRepoServerStrictTLS bool
+		argocdCacheTTL            time.Duration
+		argocdCacheSize           int
 		configMapName             string
 		secretName                string
+		leaderElect               bool
+		leaderElectLeaseName      string
+		leaderElectLeaseDuration  time.Duration
+		leaderElectRenewDeadline  time.Duration
+		leaderElectRetryPeriod    time.Duration
+		shutdownTimeout           time.Duration
+		applicationNamespaces     []string
+		shard                     int
+		shardCount                int
+		applicationSets           bool
+		recordEvents              bool
+		historySize               int
+		serveHistory              bool
+		historyConfigMapName      string
+		deadLetterSize            int
+		deadLetterConfigMapName   string
+		digestConfigMapName       string
+		stateStoreType            string
+		stateConfigMapName        string
+		dryRun                    bool
+		maxSyncStatusRefreshWait  time.Duration
+		tracingOpts               tracing.Options
+		debugPort                 int
+		appCachePruning           bool
+		appCacheFields            []string
 	)
 	var command = cobra.Command{
 		Use:   "controller",
@@ -63,6 +122,16 @@ func newControllerCommand() *cobra.Command {
 					return err
 				}
 			}
+			if shardCount < 1 {
+				return fmt.Errorf("shard count must be positive, got %d", shardCount)
+			}
+			if shard < 0 || shard >= shardCount {
+				return fmt.Errorf("shard must be between 0 and %d, got %d", shardCount-1, shard)
+			}
+			if dryRun && leaderElect {
+				// the dry-run controller must not take over the lease of the controller which sends notifications
+				return errors.New("leader election cannot be enabled in dry-run mode")
+			}
 			level, err := log.ParseLevel(logLevel)
 			if err != nil {
 				return err
@@ -80,32 +149,171 @@ func newControllerCommand() *cobra.Command {
 				return fmt.Errorf("Unknown log format '%s'", logFormat)
 			}
 
+			shutdownTracing, err := tracing.Init(context.Background(), "argocd-notifications-controller", tracingOpts)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+				defer cancel()
+				if err := shutdownTracing(ctx); err != nil {
+					log.Warnf("Failed to flush traces: %v", err)
+				}
+			}()
+
 			argocdService, err := argocd.NewArgoCDService(k8sClient, namespace, argocdRepoServer, argocdRepoServerPlaintext, argocdRepoServerStrictTLS)
 			if err != nil {
 				return err
 			}
 			defer argocdService.Close()
+			cachingArgocdService, err := argocd.NewCachingService(argocdService, argocdCacheTTL, argocdCacheSize)
+			if err != nil {
+				return err
+			}
 
 			k8s.ConfigMapName = configMapName
 			k8s.SecretName = secretName
 
 			registry := notificationscontroller.NewMetricsRegistry("argocd")
-			http.Handle("/metrics", promhttp.HandlerFor(prometheus.Gatherers{registry, prometheus.DefaultGatherer}, promhttp.HandlerOpts{}))
+
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+
+			if dryRun {
+				// nothing but logs and metrics is written in dry-run mode
+				log.Info("Running in dry-run mode: notifications are not sent")
+				recordEvents = false
+				historySize = 0
+				deadLetterSize = 0
+				digestConfigMapName = ""
+			}
+
+			var recorder record.EventRecorder
+			if recordEvents {
+				broadcaster := record.NewBroadcaster()
+				broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: k8sClient.CoreV1().Events("")})
+				defer broadcaster.Shutdown()
+				recorder = broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "argocd-notifications-controller"})
+			}
+
+			mux := http.NewServeMux()
+			var historyStore *history.Store
+			if historySize > 0 {
+				if shardCount > 1 {
+					historyConfigMapName = fmt.Sprintf("%s-%d", historyConfigMapName, shard)
+				}
+				historyStore = history.NewConfigMapStore(k8sClient, namespace, historyConfigMapName, historySize)
+				go historyStore.Run(ctx, historyPersistInterval)
+				defer historyStore.Persist(context.Background())
+				if serveHistory {
+					mux.Handle("/history", historyStore)
+				}
+			}
+
+			var deadLetters *deadletter.Store
+			if deadLetterSize > 0 {
+				if shardCount > 1 {
+					deadLetterConfigMapName = fmt.Sprintf("%s-%d", deadLetterConfigMapName, shard)
+				}
+				deadLetters = deadletter.NewConfigMapStore(k8sClient, namespace, deadLetterConfigMapName, deadLetterSize)
+			}
+
+			var digestStore *digest.Store
+			if digestConfigMapName != "" {
+				if shardCount > 1 {
+					digestConfigMapName = fmt.Sprintf("%s-%d", digestConfigMapName, shard)
+				}
+				digestStore = digest.NewConfigMapStore(k8sClient, namespace, digestConfigMapName)
+			}
+
+			var stateStore state.Store
+			switch stateStoreType {
+			case stateStoreAnnotation:
+			case stateStoreConfigMap:
+				stateStore = state.NewConfigMapStore(ctx, k8sClient, stateConfigMapName)
+			default:
+				return fmt.Errorf("unknown state store '%s'", stateStoreType)
+			}
+
+			ctrl := controller.NewController(k8sClient, dynamicClient, cachingArgocdService, namespace, appLabelSelector, registry,
+				controller.WithShutdownTimeout(shutdownTimeout),
+				controller.WithApplicationNamespaces(applicationNamespaces),
+				controller.WithSharding(shard, shardCount),
+				controller.WithApplicationSets(applicationSets),
+				controller.WithEventRecorder(recorder),
+				controller.WithHistory(historyStore),
+				controller.WithDeadLetters(deadLetters),
+				controller.WithDigestStore(digestStore),
+				controller.WithStateStore(stateStore),
+				controller.WithDryRun(dryRun),
+				controller.WithMaxSyncStatusRefreshWait(maxSyncStatusRefreshWait),
+				controller.WithAppCachePruning(appCachePruning, appCacheFields))
+
+			mux.Handle("/metrics", promhttp.HandlerFor(prometheus.Gatherers{registry, prometheus.DefaultGatherer}, promhttp.HandlerOpts{}))
+			mux.HandleFunc("/healthz", healthz.LivenessHandler)
+			mux.Handle("/readyz", healthz.NewReadinessHandler(map[string]healthz.Checker{
+				"caches":      ctrl.CheckCaches,
+				"config":      ctrl.CheckConfig,
+				"repo-server": argocdService.CheckConnection,
+			}))
 
 			go func() {
-				log.Fatal(http.ListenAndServe(fmt.Sprintf("0.0.0.0:%d", metricsPort), http.DefaultServeMux))
+				log.Fatal(http.ListenAndServe(fmt.Sprintf("0.0.0.0:%d", metricsPort), mux))
 			}()
 			log.Infof("serving metrics on port %d", metricsPort)
+			debug.Serve(debugPort, debug.NewState(c.Flags(), namespace, ctrl.Informers))
 			log.Infof("loading configuration %d", metricsPort)
 
-			ctrl := controller.NewController(k8sClient, dynamicClient, argocdService, namespace, appLabelSelector, registry)
-			err = ctrl.Init(context.Background())
+			err = ctrl.Init(ctx)
 			if err != nil {
 				return err
 			}
 
-			go ctrl.Run(context.Background(), processorsCount)
-			<-context.Background().Done()
+			if !leaderElect {
+				ctrl.Run(ctx, processorsCount)
+				log.Info("controller stopped")
+				return nil
+			}
+
+			if shardCount > 1 {
+				// replicas of each shard compete for the lease of the shard
+				leaderElectLeaseName = fmt.Sprintf("%s-%d", leaderElectLeaseName, shard)
+			}
+			lock, err := newLeaseLock(k8sClient, namespace, leaderElectLeaseName)
+			if err != nil {
+				return err
+			}
+			leading := make(chan struct{})
+			// The lease is not released on shutdown: it stays valid for the lease duration and keeps
+			// standby replicas from sending notifications while in-flight notifications are drained.
+			go leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
+				Lock:          lock,
+				LeaseDuration: leaderElectLeaseDuration,
+				RenewDeadline: leaderElectRenewDeadline,
+				RetryPeriod:   leaderElectRetryPeriod,
+				Callbacks: leaderelection.LeaderCallbacks{
+					OnStartedLeading: func(_ context.Context) {
+						log.Infof("started leading, lease %s/%s", namespace, leaderElectLeaseName)
+						close(leading)
+					},
+					OnStoppedLeading: func() {
+						if ctx.Err() == nil {
+							log.Fatalf("lost leadership of lease %s/%s", namespace, leaderElectLeaseName)
+						}
+					},
+					OnNewLeader: func(identity string) {
+						if identity != lock.Identity() {
+							log.Infof("current leader is %s, running as standby", identity)
+						}
+					},
+				},
+			})
+			select {
+			case <-leading:
+				ctrl.Run(ctx, processorsCount)
+			case <-ctx.Done():
+			}
+			log.Info("controller stopped")
 			return nil
 		},
 	}
@@ -113,13 +321,58 @@ func newControllerCommand() *cobra.Command {
 	command.Flags().IntVar(&processorsCount, "processors-count", 1, "Processors count.")
 	command.Flags().StringVar(&appLabelSelector, "app-label-selector", "", "App label selector.")
 	command.Flags().StringVar(&namespace, "namespace", "", "Namespace which controller handles. Current namespace if empty.")
+	command.Flags().StringSliceVar(&applicationNamespaces, "application-namespaces", nil, "Additional namespaces (glob patterns are supported) of applications which controller handles. Requires cluster-wide permissions granted by manifests/install-cluster-rbac.yaml.")
+	command.Flags().BoolVar(&applicationSets, "application-sets", false, "Enable notifications about ApplicationSets")
+	command.Flags().BoolVar(&recordEvents, "record-events", false, "Record notification delivery attempts as Kubernetes events of applications and projects")
+	command.Flags().IntVar(&historySize, "history-size", 0, "Maximum number of notification delivery attempts kept in the history. The history is disabled by default.")
+	command.Flags().BoolVar(&serveHistory, "serve-history", false, "Serve the notifications history on the /history endpoint of the metrics port. The history includes rendered notification messages, so the endpoint should not be exposed to untrusted clients.")
+	command.Flags().StringVar(&historyConfigMapName, "history-config-map", defaultHistoryCMName, "Name of the ConfigMap which persists the notifications history. The shard index is appended if sharding is enabled.")
+	command.Flags().IntVar(&deadLetterSize, "dead-letter-size", defaultDeadLetterSize, "Maximum number of notifications which could not be delivered after all retries kept in the dead-letter store. Set to 0 to disable the store.")
+	command.Flags().StringVar(&deadLetterConfigMapName, "dead-letter-config-map", deadletter.DefaultConfigMapName, "Name of the ConfigMap which stores undelivered notifications. The shard index is appended if sharding is enabled.")
+	command.Flags().StringVar(&digestConfigMapName, "digest-config-map", digest.DefaultConfigMapName, "Name of the ConfigMap which persists notifications buffered until the next digest. The shard index is appended if sharding is enabled. Set to empty string to keep digests in memory only.")
+	command.Flags().StringVar(&stateStoreType, "state-store", stateStoreAnnotation, "Where notifications state is stored. One of: annotation|configmap. The configmap store keeps the state in a ConfigMap in the namespace of each application, so that applications are not modified.")
+	command.Flags().StringVar(&stateConfigMapName, "state-config-map", state.DefaultConfigMapName, "Name prefix of the ConfigMaps which store notifications state if the configmap state store is used")
+	command.Flags().BoolVar(&dryRun, "dry-run", false, "Evaluate triggers and render notifications without sending them. Rendered notifications are logged and counted by the argocd_notifications_dry_run_total metric; notifications state, events, history and dead letters are not written.")
+	command.Flags().DurationVar(&maxSyncStatusRefreshWait, "max-sync-status-refresh-wait", 0, "Maximum duration to wait for Argo CD to refresh the application sync status after the completed operation. Once exceeded the application is processed with the staleSyncStatus field set to true. Zero means no limit.")
+	command.Flags().StringVar(&tracingOpts.OTLPAddress, "otlp-address", "", "Address of the OpenTelemetry collector which receives traces over OTLP gRPC. Tracing is disabled if neither the collector address nor the trace file is set.")
+	command.Flags().BoolVar(&tracingOpts.OTLPInsecure, "otlp-insecure", false, "Disable TLS of the connection to the OpenTelemetry collector")
+	command.Flags().StringToStringVar(&tracingOpts.OTLPHeaders, "otlp-headers", nil, "Headers sent to the OpenTelemetry collector, e.g. key1=value1,key2=value2")
+	command.Flags().StringVar(&tracingOpts.File, "trace-file", "", "File which traces are written to as JSON, intended for local testing. Use '-' to write traces to the standard output.")
+	command.Flags().Float64Var(&tracingOpts.SampleRatio, "trace-sample-ratio", 1, "Fraction of resource processing traces which are sampled")
+	command.Flags().BoolVar(&appCachePruning, "app-cache-pruning", false, "Cache only application fields used by the controller and triggers to reduce memory usage. Templates are rendered using the full application fetched before sending notifications. Applications cached before a trigger change might miss fields used by new triggers until the applications change.")
+	command.Flags().StringSliceVar(&appCacheFields, "app-cache-fields", nil, "Additional application fields, e.g. status.summary, which are cached if application cache pruning is enabled")
+	command.Flags().IntVar(&shard, "shard", 0, "Zero-based index of the applications shard which controller handles")
+	command.Flags().IntVar(&shardCount, "shard-count", 1, "Total number of shards. Each application is handled by a single shard chosen by the hash of the application key.")
 	command.Flags().StringVar(&logLevel, "loglevel", "info", "Set the logging level. One of: debug|info|warn|error")
 	command.Flags().StringVar(&logFormat, "logformat", "text", "Set the logging format. One of: text|json")
 	command.Flags().IntVar(&metricsPort, "metrics-port", defaultMetricsPort, "Metrics port")
+	command.Flags().IntVar(&debugPort, "debug-port", 0, "Port of the debug server which serves pprof profiles and the runtime state. The debug server is disabled if zero.")
 	command.Flags().StringVar(&argocdRepoServer, "argocd-repo-server", "argocd-repo-server:8081", "Argo CD repo server address")
 	command.Flags().BoolVar(&argocdRepoServerPlaintext, "argocd-repo-server-plaintext", false, "Use a plaintext client (non-TLS) to connect to repository server")
 	command.Flags().BoolVar(&argocdRepoServerStrictTLS, "argocd-repo-server-strict-tls", false, "Perform strict validation of TLS certificates when connecting to repo server")
+	command.Flags().DurationVar(&argocdCacheTTL, "argocd-cache-ttl", defaultArgoCDCacheTTL, "Duration for which commit metadata and application details returned by the repo server are cached. Set to 0 to only coalesce concurrent identical requests.")
+	command.Flags().IntVar(&argocdCacheSize, "argocd-cache-size", defaultArgoCDCacheSize, "Maximum number of cached repo server responses. Least recently used responses are evicted first.")
 	command.Flags().StringVar(&configMapName, "config-map-name", "argocd-notifications-cm", "Set notifications ConfigMap name")
 	command.Flags().StringVar(&secretName, "secret-name", "argocd-notifications-secret", "Set notifications Secret name")
+	command.Flags().BoolVar(&leaderElect, "leader-elect", false, "Enable leader election so that only one of several controller replicas sends notifications")
+	command.Flags().StringVar(&leaderElectLeaseName, "leader-elect-lease-name", defaultLeaseName, "Name of the Lease used for leader election. The shard index is appended if sharding is enabled.")
+	command.Flags().DurationVar(&leaderElectLeaseDuration, "leader-elect-lease-duration", defaultLeaseDuration, "Duration that standby replicas wait before trying to acquire a not renewed lease")
+	command.Flags().DurationVar(&leaderElectRenewDeadline, "leader-elect-renew-deadline", defaultRenewDeadline, "Duration that the leader retries renewing the lease before giving up leadership")
+	command.Flags().DurationVar(&leaderElectRetryPeriod, "leader-elect-retry-period", defaultLeaseRetryTime, "Duration between leader election attempts")
+	command.Flags().DurationVar(&shutdownTimeout, "shutdown-timeout", defaultShutdownTimeout, "Maximum duration to wait for in-flight notifications on shutdown")
 	return &command
 }
+
+func newLeaseLock(k8sClient kubernetes.Interface, namespace string, name string) (*resourcelock.LeaseLock, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	return &resourcelock.LeaseLock{
+		LeaseMeta: v1.ObjectMeta{Name: name, Namespace: namespace},
+		Client:    k8sClient.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: fmt.Sprintf("%s_%s", hostname, uuid.NewUUID()),
+		},
+	}, nil
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	// embed the time zone database so that quiet windows time zones are resolved in images without it
+	_ "time/tzdata"
 
 	"github.com/argoproj-labs/argocd-notifications/cmd/tools"
 
@@ -0,0 +1,211 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/argoproj-labs/argocd-notifications/controller/deadletter"
+	"github.com/argoproj-labs/argocd-notifications/shared/k8s"
+	"github.com/argoproj-labs/argocd-notifications/shared/settings"
+
+	"github.com/argoproj/notifications-engine/pkg/api"
+	"github.com/argoproj/notifications-engine/pkg/services"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// newDeadLettersCommand creates the command which manages the dead-letter store. The getClients function returns the
+// Kubernetes client and the namespace configured by the flags of the parent command.
+func newDeadLettersCommand(getClients func() (kubernetes.Interface, string)) *cobra.Command {
+	var configMapName string
+	command := cobra.Command{
+		Use:   "dead-letters",
+		Short: "Inspect and replay notifications that could not be delivered",
+		Run: func(c *cobra.Command, args []string) {
+			c.HelpFunc()(c, args)
+		},
+	}
+	getStore := func() (*deadletter.Store, kubernetes.Interface, string) {
+		k8sClient, namespace := getClients()
+		return deadletter.NewConfigMapStore(k8sClient, namespace, configMapName, 0), k8sClient, namespace
+	}
+	command.AddCommand(newDeadLettersListCommand(getStore))
+	command.AddCommand(newDeadLettersReplayCommand(getStore))
+	command.AddCommand(newDeadLettersDeleteCommand(getStore))
+	command.PersistentFlags().StringVar(&configMapName, "dead-letter-config-map", deadletter.DefaultConfigMapName, "Name of the ConfigMap which stores undelivered notifications")
+	return &command
+}
+
+type storeGetter func() (*deadletter.Store, kubernetes.Interface, string)
+
+func newDeadLettersListCommand(getStore storeGetter) *cobra.Command {
+	var output string
+	command := cobra.Command{
+		Use:   "list",
+		Short: "List notifications that could not be delivered",
+		Example: `
+# List undelivered notifications
+argocd-notifications tools dead-letters list
+`,
+		RunE: func(c *cobra.Command, args []string) error {
+			store, _, _ := getStore()
+			entries, err := store.List(context.Background())
+			if err != nil {
+				return err
+			}
+			return printDeadLetters(os.Stdout, entries, output)
+		},
+	}
+	command.Flags().StringVarP(&output, "output", "o", "wide", "Output format. One of:json|wide")
+	return &command
+}
+
+func printDeadLetters(out io.Writer, entries []deadletter.Entry, output string) error {
+	switch output {
+	case "json":
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, string(data))
+		return err
+	case "wide":
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintf(w, "ID\tRESOURCE\tNAME\tTRIGGER\tDESTINATION\tATTEMPTS\tERROR\n")
+		for _, e := range entries {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s/%s\t%s\t%s\t%d\t%s\n", e.ID, e.Resource, e.Namespace, e.Name, e.Trigger, formatDestination(e.Destination), e.Attempts, e.Error)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown output format: %s", output)
+	}
+}
+
+func newDeadLettersReplayCommand(getStore storeGetter) *cobra.Command {
+	var all bool
+	command := cobra.Command{
+		Use:   "replay [ID...]",
+		Short: "Send undelivered notifications again and remove delivered ones from the dead-letter store",
+		Example: `
+# Replay the specified notification
+argocd-notifications tools dead-letters replay 20211117-165611.000000000-0e1f1eda
+
+# Replay all undelivered notifications
+argocd-notifications tools dead-letters replay --all
+`,
+		RunE: func(c *cobra.Command, args []string) error {
+			if len(args) == 0 && !all {
+				return fmt.Errorf("specify notification IDs or use --all flag")
+			}
+			ctx := context.Background()
+			store, k8sClient, namespace := getStore()
+			entries, err := selectDeadLetters(ctx, store, args, all)
+			if err != nil {
+				return err
+			}
+			notificationsAPI, err := newNotificationsAPI(ctx, k8sClient, namespace)
+			if err != nil {
+				return err
+			}
+			var failed int
+			for _, e := range entries {
+				svc, ok := notificationsAPI.GetNotificationServices()[e.Destination.Service]
+				if !ok {
+					err = fmt.Errorf("notification service '%s' is not supported", e.Destination.Service)
+				} else {
+					err = svc.Send(e.Notification, e.Destination)
+				}
+				if err != nil {
+					failed++
+					_, _ = fmt.Fprintf(os.Stdout, "%s: failed to send notification to %s: %v\n", e.ID, formatDestination(e.Destination), err)
+					continue
+				}
+				if err := store.Remove(ctx, e.ID); err != nil {
+					return err
+				}
+				_, _ = fmt.Fprintf(os.Stdout, "%s: sent notification to %s\n", e.ID, formatDestination(e.Destination))
+			}
+			if failed > 0 {
+				return fmt.Errorf("failed to send %d of %d notification(s)", failed, len(entries))
+			}
+			return nil
+		},
+	}
+	command.Flags().BoolVar(&all, "all", false, "Replay all undelivered notifications")
+	return &command
+}
+
+func newDeadLettersDeleteCommand(getStore storeGetter) *cobra.Command {
+	var all bool
+	command := cobra.Command{
+		Use:   "delete [ID...]",
+		Short: "Remove undelivered notifications from the dead-letter store",
+		RunE: func(c *cobra.Command, args []string) error {
+			if len(args) == 0 && !all {
+				return fmt.Errorf("specify notification IDs or use --all flag")
+			}
+			ctx := context.Background()
+			store, _, _ := getStore()
+			entries, err := selectDeadLetters(ctx, store, args, all)
+			if err != nil {
+				return err
+			}
+			var ids []string
+			for _, e := range entries {
+				ids = append(ids, e.ID)
+			}
+			return store.Remove(ctx, ids...)
+		},
+	}
+	command.Flags().BoolVar(&all, "all", false, "Remove all undelivered notifications")
+	return &command
+}
+
+func selectDeadLetters(ctx context.Context, store *deadletter.Store, ids []string, all bool) ([]deadletter.Entry, error) {
+	entries, err := store.List(ctx)
+	if err != nil || all {
+		return entries, err
+	}
+	byID := map[string]deadletter.Entry{}
+	for _, e := range entries {
+		byID[e.ID] = e
+	}
+	var res []deadletter.Entry
+	for _, id := range ids {
+		e, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("notification %s not found", id)
+		}
+		res = append(res, e)
+	}
+	return res, nil
+}
+
+func formatDestination(dest services.Destination) string {
+	return fmt.Sprintf("%s:%s", dest.Service, dest.Recipient)
+}
+
+// newNotificationsAPI creates the API configured by the notifications ConfigMap and Secret of the cluster
+func newNotificationsAPI(ctx context.Context, k8sClient kubernetes.Interface, namespace string) (api.API, error) {
+	configMap, err := k8sClient.CoreV1().ConfigMaps(namespace).Get(ctx, k8s.ConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	secret, err := k8sClient.CoreV1().Secrets(namespace).Get(ctx, k8s.SecretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := api.ParseConfig(configMap, secret)
+	if err != nil {
+		return nil, err
+	}
+	if err := settings.ApplyLegacyConfig(cfg, map[string]string{}, configMap, secret); err != nil {
+		return nil, err
+	}
+	return api.NewAPI(*cfg, nil)
+}
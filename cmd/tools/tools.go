@@ -20,12 +20,16 @@ func NewToolsCommand() *cobra.Command {
 		argocdRepoServerStrictTLS bool
 	)
 
-	var argocdService argocd.Service
+	var (
+		argocdService argocd.Service
+		k8sClient     kubernetes.Interface
+		namespace     string
+	)
 	toolsCommand := cmd.NewToolsCommand(
 		"argocd-notifications",
 		"argocd-notifications",
 		k8s.Applications,
-		settings.GetFactorySettings(argocdService), func(clientConfig clientcmd.ClientConfig) {
+		settings.GetFactorySettings(argocdService, nil), func(clientConfig clientcmd.ClientConfig) {
 			k8sCfg, err := clientConfig.ClientConfig()
 			if err != nil {
 				log.Fatalf("Failed to parse k8s config: %v", err)
@@ -34,11 +38,16 @@ func NewToolsCommand() *cobra.Command {
 			if err != nil {
 				log.Fatalf("Failed to parse k8s config: %v", err)
 			}
-			argocdService, err = argocd.NewArgoCDService(kubernetes.NewForConfigOrDie(k8sCfg), ns, argocdRepoServer, argocdRepoServerPlaintext, argocdRepoServerStrictTLS)
+			k8sClient = kubernetes.NewForConfigOrDie(k8sCfg)
+			namespace = ns
+			argocdService, err = argocd.NewArgoCDService(k8sClient, ns, argocdRepoServer, argocdRepoServerPlaintext, argocdRepoServerStrictTLS)
 			if err != nil {
 				log.Fatalf("Failed to initalize Argo CD service: %v", err)
 			}
 		})
+	toolsCommand.AddCommand(newDeadLettersCommand(func() (kubernetes.Interface, string) {
+		return k8sClient, namespace
+	}))
 	toolsCommand.PersistentFlags().StringVar(&argocdRepoServer, "argocd-repo-server", "argocd-repo-server:8081", "Argo CD repo server address")
 	toolsCommand.PersistentFlags().BoolVar(&argocdRepoServerPlaintext, "argocd-repo-server-plaintext", false, "Use a plaintext client (non-TLS) to connect to repository server")
 	toolsCommand.PersistentFlags().BoolVar(&argocdRepoServerStrictTLS, "argocd-repo-server-strict-tls", false, "Perform strict validation of TLS certificates when connecting to repo server")
@@ -0,0 +1,376 @@
+package controller
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/argoproj-labs/argocd-notifications/shared/k8s"
+	"github.com/argoproj-labs/argocd-notifications/shared/settings"
+	"github.com/argoproj-labs/argocd-notifications/shared/tracing"
+
+	"github.com/argoproj/notifications-engine/pkg/api"
+	"github.com/argoproj/notifications-engine/pkg/services"
+	"github.com/argoproj/notifications-engine/pkg/templates"
+	"github.com/argoproj/notifications-engine/pkg/triggers"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	serviceTypeVarName = "serviceType"
+	recipientVarName   = "recipient"
+)
+
+// notificationsAPIFactory wraps the API produced by the notifications-engine factory so that every notification
+// sent by the notifications-engine controller goes through notificationsAPI
+type notificationsAPIFactory struct {
+	factory  api.Factory
+	resource string
+	ctrl     *notificationController
+
+	lock          sync.Mutex
+	getVars       api.GetVars
+	retryPolicies settings.RetryPolicies
+	rateLimits    settings.RateLimitPolicies
+	digests       settings.Digests
+	quietWindows  settings.QuietWindows
+	api           *notificationsAPI
+}
+
+// newAPIFactory creates the factory of the API which sends notifications about the specified resource
+func (c *notificationController) newAPIFactory(resource string, apiSettings api.Settings) *notificationsAPIFactory {
+	res := &notificationsAPIFactory{resource: resource, ctrl: c}
+	initGetVars := apiSettings.InitGetVars
+	apiSettings.InitGetVars = func(cfg *api.Config, configMap *v1.ConfigMap, secret *v1.Secret) (api.GetVars, error) {
+		getVars, err := initGetVars(cfg, configMap, secret)
+		if err != nil {
+			return nil, err
+		}
+		retryPolicies, err := settings.ParseRetryPolicies(configMap)
+		if err != nil {
+			return nil, err
+		}
+		rateLimits, err := settings.ParseRateLimitPolicies(configMap)
+		if err != nil {
+			return nil, err
+		}
+		digests, err := settings.ParseDigests(configMap)
+		if err != nil {
+			return nil, err
+		}
+		quietWindows, err := settings.ParseQuietWindows(configMap)
+		if err != nil {
+			return nil, err
+		}
+		if resource == k8s.Applications.Resource && c.appPruner != nil {
+			c.appPruner.setTriggers(cfg.Triggers)
+		}
+		res.lock.Lock()
+		res.getVars = getVars
+		res.retryPolicies = retryPolicies
+		res.rateLimits = rateLimits
+		res.digests = digests
+		res.quietWindows = quietWindows
+		res.lock.Unlock()
+		return getVars, nil
+	}
+	res.factory = api.NewFactory(apiSettings, c.namespace, c.secretInformer, c.configMapInformer)
+	c.apiFactories[resource] = res
+	return res
+}
+
+// getAPI returns the API which sends notifications about the given resource
+func (c *notificationController) getAPI(resource string) (*notificationsAPI, error) {
+	factory, ok := c.apiFactories[resource]
+	if !ok {
+		return nil, fmt.Errorf("notifications about %s are not sent", resource)
+	}
+	if _, err := factory.GetAPI(); err != nil {
+		return nil, err
+	}
+	factory.lock.Lock()
+	defer factory.lock.Unlock()
+	return factory.api, nil
+}
+
+func (f *notificationsAPIFactory) GetAPI() (api.API, error) {
+	res, err := f.factory.GetAPI()
+	if err != nil {
+		return nil, err
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.api == nil || f.api.API != res {
+		templatesService, err := templates.NewService(res.GetConfig().Templates)
+		if err != nil {
+			return nil, err
+		}
+		f.api = &notificationsAPI{
+			API:           res,
+			templates:     templatesService,
+			getVars:       f.getVars,
+			retryPolicies: f.retryPolicies,
+			rateLimits:    f.rateLimits,
+			digests:       f.digests,
+			quietWindows:  f.quietWindows,
+			resource:      f.resource,
+			ctrl:          f.ctrl,
+		}
+	}
+	return f.api, nil
+}
+
+// notificationsAPI renders and sends notifications instead of the notifications-engine API so that the controller
+// has access to the rendered notification of every delivery attempt
+type notificationsAPI struct {
+	api.API
+	templates     templates.Service
+	getVars       api.GetVars
+	retryPolicies settings.RetryPolicies
+	rateLimits    settings.RateLimitPolicies
+	digests       settings.Digests
+	quietWindows  settings.QuietWindows
+	resource      string
+	ctrl          *notificationController
+}
+
+// triggerRun holds the result of the trigger evaluated for the resource
+type triggerRun struct {
+	trigger string
+	results []triggers.ConditionResult
+}
+
+// conditionKey returns the key of the triggered condition which sends the given templates
+func (r triggerRun) conditionKey(templates []string) string {
+	for _, res := range r.results {
+		if res.Triggered && fmt.Sprint(res.Templates) == fmt.Sprint(templates) {
+			return res.Key
+		}
+	}
+	return ""
+}
+
+// RunTrigger remembers the trigger evaluated for the resource: the notifications-engine controller sends
+// notifications right after the trigger evaluation and never processes the same resource concurrently.
+func (a *notificationsAPI) RunTrigger(trigger string, vars map[string]interface{}) ([]triggers.ConditionResult, error) {
+	key := a.resource + "/" + resourceKey(vars)
+	_, step := a.ctrl.tracer.startStep(key, "RunTrigger", attribute.String("trigger", trigger))
+	res, err := a.API.RunTrigger(trigger, vars)
+	triggered := 0
+	for _, cr := range res {
+		if cr.Triggered {
+			triggered++
+		}
+	}
+	step.SetAttributes(attribute.Int("triggered", triggered))
+	step.end(err)
+	if a.ctrl.dryRun != nil && a.ctrl.registry != nil && err == nil {
+		for _, cr := range res {
+			a.ctrl.registry.IncTriggerEvaluationsCounter(trigger, cr.Triggered)
+		}
+	}
+	if triggered > 0 {
+		a.ctrl.lastTriggerRuns.Store(key, triggerRun{trigger: trigger, results: res})
+	} else {
+		// notifications are sent only if the trigger is triggered
+		a.ctrl.lastTriggerRuns.Delete(key)
+	}
+	return res, err
+}
+
+func (a *notificationsAPI) Send(obj map[string]interface{}, templates []string, dest services.Destination) (err error) {
+	key := a.resource + "/" + resourceKey(obj)
+	var run triggerRun
+	if val, ok := a.ctrl.lastTriggerRuns.Load(key); ok {
+		run = val.(triggerRun)
+	}
+	ctx, step := a.ctrl.tracer.startStep(key, "Send",
+		attribute.String("trigger", run.trigger),
+		attribute.String("destination", formatDestination(dest)),
+		attribute.StringSlice("templates", templates))
+	defer func() {
+		step.end(err)
+	}()
+	if !a.ctrl.drainer.startSend() {
+		return errShuttingDown
+	}
+	if a.resource == k8s.Applications.Resource && a.ctrl.appPruner != nil {
+		// the cached application is pruned, so templates are rendered using the full application
+		if obj, err = a.ctrl.getFullApp(obj); err != nil {
+			a.ctrl.drainer.finishSend(key, false)
+			return err
+		}
+	}
+	d := delivery{
+		ctx:          ctx,
+		resource:     a.resource,
+		obj:          &unstructured.Unstructured{Object: obj},
+		trigger:      run.trigger,
+		conditionKey: run.conditionKey(templates),
+		templates:    templates,
+		destination:  dest,
+	}
+	_, renderStep := a.ctrl.tracer.startStep(key, "Render")
+	d.notification, d.err = a.render(obj, templates, dest)
+	renderStep.end(d.err)
+	if a.ctrl.dryRun != nil {
+		step.SetAttributes(attribute.String("result", "dry-run"))
+		a.ctrl.dryRun.record(d)
+		a.ctrl.drainer.finishSend(key, d.err == nil)
+		return d.err
+	}
+	if d.err == nil && a.hold(key, d) {
+		// the held notification is considered as sent so that it is not sent again
+		step.SetAttributes(attribute.String("result", "held"))
+		a.ctrl.drainer.finishSend(key, true)
+		return nil
+	}
+	if d.err == nil {
+		delay, allowed := a.ctrl.rateLimiter.reserve(a.rateLimits.Get(dest.Service), key, dest)
+		if !allowed {
+			// the dropped notification is considered as sent so that it is not sent again once the limit allows
+			log.Infof("Dropped notification about %s to %s: rate limit exceeded", key, formatDestination(dest))
+			step.SetAttributes(attribute.String("result", "rate-limited"))
+			a.ctrl.rateLimiter.suppress(a.GetNotificationServices()[dest.Service], dest, delay)
+			a.ctrl.drainer.finishSend(key, true)
+			return nil
+		}
+		if delay > 0 {
+			// the queued notification is considered as sent and the notifications state is persisted before the
+			// notification is actually sent, so the resource is not waited for while draining. The queued notification
+			// is sent right away once draining is started so that it is not lost on shutdown.
+			log.Infof("Delayed notification about %s to %s by %v: rate limit exceeded", key, formatDestination(dest), delay)
+			step.SetAttributes(attribute.String("result", "delayed"), attribute.String("delay", delay.String()))
+			go func() {
+				select {
+				case <-time.After(delay):
+				case <-a.ctrl.drainer.stopping():
+				}
+				finish := func() { a.ctrl.drainer.finishSend(key, false) }
+				if !a.deliverAndRecord(&d, finish) {
+					finish()
+				}
+			}()
+			return nil
+		}
+	}
+	if a.deliverAndRecord(&d, func() { a.ctrl.drainer.finishSend(key, false) }) {
+		// the retried notification is considered as sent so that the worker is not blocked by the retries
+		step.SetAttributes(attribute.String("result", "retrying"))
+		a.ctrl.drainer.markSent(key)
+		return nil
+	}
+	a.ctrl.drainer.finishSend(key, d.err == nil)
+	return d.err
+}
+
+// hold drops or buffers the notification muted by a quiet window and buffers the notification to the digest
+// destination. Returns false if the notification should be sent right away.
+func (a *notificationsAPI) hold(key string, d delivery) bool {
+	dest := d.destination
+	if window, end := a.ctrl.getActiveQuietWindow(a.quietWindows, a.resource, d.obj, d.trigger, time.Now()); window != nil {
+		log.Infof("Muted notification about %s to %s by quiet window %s", key, formatDestination(dest), window.Name)
+		if window.Action == settings.QuietWindowActionSummary {
+			a.ctrl.digester.add(a, "quiet window "+window.Name, window.SummaryTemplate, end, dest, d.obj, d.trigger, d.notification)
+		}
+		return true
+	}
+	if digest := a.ctrl.getDigest(a.digests, a.resource, d.obj, d.trigger, dest); digest != nil {
+		log.Infof("Added notification about %s to digest %s of %s", key, digest.Name, formatDestination(dest))
+		a.ctrl.digester.addToDigest(a, *digest, dest, d.obj, d.trigger, d.notification)
+		return true
+	}
+	return false
+}
+
+// deliverAndRecord sends the rendered notification and records the delivery attempt. If the first attempt fails and
+// the retry policy of the service allows more attempts then the notification is retried in the background, so the
+// caller is not blocked between attempts: the delivery is recorded and the given function is called once the retries
+// are over. Returns true if the notification is being retried.
+func (a *notificationsAPI) deliverAndRecord(d *delivery, retried func()) bool {
+	if d.err != nil {
+		a.ctrl.recordDelivery(a.GetConfig(), *d)
+		return false
+	}
+	_, span := a.ctrl.tracer.tracer.Start(d.ctx, "Deliver", trace.WithAttributes(attribute.String("destination", formatDestination(d.destination))))
+	d.attempts, d.err = 1, a.sendOnce(*d.notification, d.destination)
+	record := func(d delivery) {
+		span.SetAttributes(attribute.Int("attempts", d.attempts))
+		tracing.End(span, d.err)
+		a.ctrl.recordDelivery(a.GetConfig(), d)
+	}
+	if d.err != nil && a.retryPolicies.Get(d.destination.Service).MaxAttempts > 1 {
+		retry := *d
+		go func() {
+			retry.attempts, retry.err = a.retry(*retry.notification, retry.destination, retry.err)
+			record(retry)
+			retried()
+		}()
+		return true
+	}
+	record(*d)
+	return false
+}
+
+// render formats the notification the same way as the notifications-engine API does
+func (a *notificationsAPI) render(obj map[string]interface{}, templates []string, dest services.Destination) (*services.Notification, error) {
+	if _, ok := a.GetNotificationServices()[dest.Service]; !ok {
+		return nil, fmt.Errorf("notification service '%s' is not supported", dest.Service)
+	}
+	in := make(map[string]interface{})
+	if a.getVars != nil {
+		for k, v := range a.getVars(obj, dest) {
+			in[k] = v
+		}
+	}
+	in[serviceTypeVarName] = dest.Service
+	in[recipientVarName] = dest.Recipient
+	return a.templates.FormatNotification(in, templates...)
+}
+
+// deliver sends the notification retrying failed attempts according to the retry policy of the service
+func (a *notificationsAPI) deliver(notification services.Notification, dest services.Destination) (int, error) {
+	if err := a.sendOnce(notification, dest); err != nil {
+		return a.retry(notification, dest, err)
+	}
+	return 1, nil
+}
+
+func (a *notificationsAPI) sendOnce(notification services.Notification, dest services.Destination) error {
+	svc, ok := a.GetNotificationServices()[dest.Service]
+	if !ok {
+		return fmt.Errorf("notification service '%s' is not supported", dest.Service)
+	}
+	return svc.Send(notification, dest)
+}
+
+// retry sends the notification after the failed first attempt according to the retry policy of the service and
+// returns the total number of attempts. Retries are stopped once the controller starts shutting down.
+func (a *notificationsAPI) retry(notification services.Notification, dest services.Destination, err error) (int, error) {
+	policy := a.retryPolicies.Get(dest.Service)
+	attempt := 1
+	for ; err != nil && attempt < policy.MaxAttempts; attempt++ {
+		delay := policy.Delay(attempt)
+		log.Warnf("Failed to send notification to %s (attempt %d of %d), retrying in %v: %v", formatDestination(dest), attempt, policy.MaxAttempts, delay, err)
+		select {
+		case <-time.After(delay):
+		case <-a.ctrl.drainer.stopping():
+			return attempt, err
+		}
+		err = a.sendOnce(notification, dest)
+	}
+	return attempt, err
+}
+
+func resourceKey(obj map[string]interface{}) string {
+	key, err := cache.MetaNamespaceKeyFunc(&unstructured.Unstructured{Object: obj})
+	if err != nil {
+		return ""
+	}
+	return key
+}
@@ -0,0 +1,266 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/argoproj/notifications-engine/pkg/controller"
+	"github.com/argoproj/notifications-engine/pkg/mocks"
+	"github.com/argoproj/notifications-engine/pkg/services"
+	"github.com/argoproj/notifications-engine/pkg/templates"
+	"github.com/argoproj/notifications-engine/pkg/triggers"
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/argoproj-labs/argocd-notifications/controller/deadletter"
+	"github.com/argoproj-labs/argocd-notifications/controller/history"
+	"github.com/argoproj-labs/argocd-notifications/shared/k8s"
+	"github.com/argoproj-labs/argocd-notifications/shared/settings"
+	. "github.com/argoproj-labs/argocd-notifications/testing"
+)
+
+type fakeService struct {
+	lock   sync.Mutex
+	sent   []services.Notification
+	errors map[string]error
+	// failures is the number of attempts that fail before the service starts to work
+	failures int
+}
+
+func (s *fakeService) Send(notification services.Notification, dest services.Destination) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := s.errors[dest.Recipient]; err != nil {
+		return err
+	}
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("service unavailable")
+	}
+	s.sent = append(s.sent, notification)
+	return nil
+}
+
+func (s *fakeService) sentCount() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.sent)
+}
+
+func newTestAPI(t *testing.T, mockAPI *mocks.MockAPI, ctrl *notificationController) *notificationsAPI {
+	templatesService, err := templates.NewService(map[string]services.Notification{
+		"my-template": {Message: "{{.app.metadata.name}} sent to {{.recipient}}"},
+	})
+	assert.NoError(t, err)
+	return &notificationsAPI{
+		API:       mockAPI,
+		templates: templatesService,
+		getVars: func(obj map[string]interface{}, _ services.Destination) map[string]interface{} {
+			return map[string]interface{}{"app": obj}
+		},
+		resource: k8s.Applications.Resource,
+		ctrl:     ctrl,
+	}
+}
+
+func TestSend_RendersNotification(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	app := NewApp("test")
+	ctrl, mockAPI, err := newController(t, ctx, NewFakeClient(app))
+	assert.NoError(t, err)
+	svc := &fakeService{}
+	mockAPI.EXPECT().GetNotificationServices().Return(map[string]services.NotificationService{"mock": svc}).AnyTimes()
+
+	notificationsAPI := newTestAPI(t, mockAPI, ctrl)
+	assert.NoError(t, notificationsAPI.Send(app.Object, []string{"my-template"}, services.Destination{Service: "mock", Recipient: "recipient"}))
+	assert.Error(t, notificationsAPI.Send(app.Object, []string{"my-template"}, services.Destination{Service: "unknown", Recipient: "recipient"}))
+
+	assert.Equal(t, []services.Notification{{Message: "test sent to recipient"}}, svc.sent)
+}
+
+func TestSend_RecordsHistory(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	app := NewApp("test")
+	store := history.NewConfigMapStore(fake.NewSimpleClientset(), TestNamespace, "history", 10)
+	ctrl, mockAPI, err := newController(t, ctx, NewFakeClient(app), WithHistory(store))
+	assert.NoError(t, err)
+	mockAPI.EXPECT().RunTrigger("my-trigger", gomock.Any()).Return([]triggers.ConditionResult{{
+		Key: "[0].abc", Triggered: true, Templates: []string{"my-template"},
+	}}, nil)
+	mockAPI.EXPECT().GetNotificationServices().Return(map[string]services.NotificationService{
+		"mock": &fakeService{errors: map[string]error{"other": errors.New("boom")}},
+	}).AnyTimes()
+
+	notificationsAPI := newTestAPI(t, mockAPI, ctrl)
+	_, err = notificationsAPI.RunTrigger("my-trigger", app.Object)
+	assert.NoError(t, err)
+	_ = notificationsAPI.Send(app.Object, []string{"my-template"}, services.Destination{Service: "mock", Recipient: "recipient"})
+	_ = notificationsAPI.Send(app.Object, []string{"my-template"}, services.Destination{Service: "mock", Recipient: "other"})
+
+	entries := store.List(history.Filter{})
+	if !assert.Len(t, entries, 2) {
+		return
+	}
+	assert.Equal(t, history.ResultFailed, entries[0].Result)
+	assert.Equal(t, "boom", entries[0].Error)
+	assert.Equal(t, history.ResultSent, entries[1].Result)
+	assert.Equal(t, "my-trigger", entries[1].Trigger)
+	assert.Equal(t, "[0].abc", entries[1].ConditionHash)
+	assert.Equal(t, "test sent to recipient", entries[1].Message)
+	assert.Equal(t, "test", entries[1].Name)
+	assert.Equal(t, TestNamespace, entries[1].Namespace)
+}
+
+func TestSend_RetriesFailedDelivery(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	app := NewApp("test")
+	ctrl, mockAPI, err := newController(t, ctx, NewFakeClient(app))
+	assert.NoError(t, err)
+	svc := &fakeService{failures: 2}
+	mockAPI.EXPECT().GetNotificationServices().Return(map[string]services.NotificationService{"mock": svc}).AnyTimes()
+
+	notificationsAPI := newTestAPI(t, mockAPI, ctrl)
+	notificationsAPI.retryPolicies = settings.RetryPolicies{Default: settings.RetryPolicy{
+		MaxAttempts:  3,
+		InitialDelay: metav1.Duration{Duration: time.Millisecond},
+		MaxDelay:     metav1.Duration{Duration: time.Millisecond},
+		Factor:       2,
+	}}
+	assert.NoError(t, notificationsAPI.Send(app.Object, []string{"my-template"}, services.Destination{Service: "mock", Recipient: "recipient"}))
+	assert.Eventually(t, func() bool {
+		return svc.sentCount() == 1
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestSend_StoresDeadLetter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	app := NewApp("test")
+	store := deadletter.NewConfigMapStore(fake.NewSimpleClientset(), TestNamespace, deadletter.DefaultConfigMapName, 10)
+	ctrl, mockAPI, err := newController(t, ctx, NewFakeClient(app), WithDeadLetters(store))
+	assert.NoError(t, err)
+	mockAPI.EXPECT().GetNotificationServices().Return(map[string]services.NotificationService{
+		"mock": &fakeService{errors: map[string]error{"other": errors.New("boom")}},
+	}).AnyTimes()
+
+	notificationsAPI := newTestAPI(t, mockAPI, ctrl)
+	notificationsAPI.retryPolicies = settings.RetryPolicies{Default: settings.RetryPolicy{MaxAttempts: 2}}
+	assert.NoError(t, notificationsAPI.Send(app.Object, []string{"my-template"}, services.Destination{Service: "mock", Recipient: "other"}))
+	assert.Error(t, notificationsAPI.Send(app.Object, []string{"my-template"}, services.Destination{Service: "unknown", Recipient: "other"}))
+
+	var entries []deadletter.Entry
+	assert.Eventually(t, func() bool {
+		entries, err = store.List(ctx)
+		return err == nil && len(entries) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	if !assert.Len(t, entries, 1) {
+		return
+	}
+	assert.Equal(t, 2, entries[0].Attempts)
+	assert.Equal(t, "boom", entries[0].Error)
+	assert.Equal(t, "test sent to other", entries[0].Notification.Message)
+	assert.Equal(t, services.Destination{Service: "mock", Recipient: "other"}, entries[0].Destination)
+}
+
+func TestSend_DropsNotificationOverRateLimit(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	app := NewApp("test")
+	ctrl, mockAPI, err := newController(t, ctx, NewFakeClient(app))
+	assert.NoError(t, err)
+	svc := &fakeService{}
+	mockAPI.EXPECT().GetNotificationServices().Return(map[string]services.NotificationService{"mock": svc}).AnyTimes()
+
+	notificationsAPI := newTestAPI(t, mockAPI, ctrl)
+	notificationsAPI.rateLimits = settings.RateLimitPolicies{Default: &settings.RateLimitPolicy{
+		Application: &settings.Limit{Limit: 1, Interval: metav1.Duration{Duration: time.Hour}},
+		Action:      settings.RateLimitActionDrop,
+	}}
+	dest := services.Destination{Service: "mock", Recipient: "recipient"}
+	assert.NoError(t, notificationsAPI.Send(app.Object, []string{"my-template"}, dest))
+	assert.NoError(t, notificationsAPI.Send(app.Object, []string{"my-template"}, dest))
+
+	assert.Len(t, svc.sent, 1)
+	assert.Len(t, ctrl.rateLimiter.suppressed, 1)
+}
+
+func TestSend_SendsDelayedNotificationOnDrain(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	app := NewApp("test")
+	ctrl, mockAPI, err := newController(t, ctx, NewFakeClient(app))
+	assert.NoError(t, err)
+	svc := &fakeService{}
+	mockAPI.EXPECT().GetNotificationServices().Return(map[string]services.NotificationService{"mock": svc}).AnyTimes()
+
+	notificationsAPI := newTestAPI(t, mockAPI, ctrl)
+	notificationsAPI.rateLimits = settings.RateLimitPolicies{Default: &settings.RateLimitPolicy{
+		Application:   &settings.Limit{Limit: 1, Interval: metav1.Duration{Duration: time.Minute}},
+		Action:        settings.RateLimitActionQueue,
+		MaxQueueDelay: metav1.Duration{Duration: time.Hour},
+	}}
+	dest := services.Destination{Service: "mock", Recipient: "recipient"}
+	assert.NoError(t, notificationsAPI.Send(app.Object, []string{"my-template"}, dest))
+	assert.NoError(t, notificationsAPI.Send(app.Object, []string{"my-template"}, dest))
+	assert.Equal(t, 1, svc.sentCount())
+
+	ctrl.drainer.persisted(k8s.Applications.Resource + "/" + resourceKey(app.Object))
+	assert.True(t, ctrl.drainer.drain(5*time.Second))
+	assert.Equal(t, 2, svc.sentCount())
+}
+
+func TestSend_DryRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	app := NewApp("test")
+	client := NewFakeClient(app)
+	var patches []map[string]interface{}
+	AddPatchCollectorReactor(client, &patches)
+	ctrl, mockAPI, err := newController(t, ctx, client, WithDryRun(true))
+	assert.NoError(t, err)
+	svc := &fakeService{}
+	mockAPI.EXPECT().GetNotificationServices().Return(map[string]services.NotificationService{"mock": svc}).AnyTimes()
+
+	notificationsAPI := newTestAPI(t, mockAPI, ctrl)
+	assert.NoError(t, notificationsAPI.Send(app.Object, []string{"my-template"}, services.Destination{Service: "mock", Recipient: "recipient"}))
+	assert.Error(t, notificationsAPI.Send(app.Object, []string{"my-template"}, services.Destination{Service: "unknown", Recipient: "recipient"}))
+	assert.Empty(t, svc.sent)
+	assert.Equal(t, float64(1), testutil.ToFloat64(ctrl.dryRun.counter.WithLabelValues(k8s.Applications.Resource, "", "mock", "true")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(ctrl.dryRun.counter.WithLabelValues(k8s.Applications.Resource, "", "unknown", "false")))
+
+	resClient := &resourceClient{NamespaceableResourceInterface: client.Resource(k8s.Applications), resource: k8s.Applications.Resource, informer: ctrl.appInformer, ctrl: ctrl}
+	patched, err := resClient.Namespace(TestNamespace).Patch(ctx, "test", types.MergePatchType,
+		[]byte(`{"metadata":{"annotations":{"notified.notifications.argoproj.io":"{\"on-deployed\":1}"}}}`), metav1.PatchOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"on-deployed":1}`, patched.GetAnnotations()[controller.NotifiedAnnotationKey])
+	assert.Empty(t, patches)
+}
+
+func TestRunTrigger_DryRunCountsTriggerEvaluations(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	app := NewApp("test")
+	ctrl, mockAPI, err := newController(t, ctx, NewFakeClient(app), WithDryRun(true))
+	assert.NoError(t, err)
+	assert.NotSame(t, ctrl.registry, ctrl.engineRegistry)
+	mockAPI.EXPECT().RunTrigger("my-trigger", gomock.Any()).Return([]triggers.ConditionResult{{Triggered: true}}, nil)
+
+	_, err = newTestAPI(t, mockAPI, ctrl).RunTrigger("my-trigger", app.Object)
+	assert.NoError(t, err)
+	count, err := testutil.GatherAndCount(ctrl.registry, "argocd_notifications_trigger_eval_total")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	count, err = testutil.GatherAndCount(ctrl.registry, "argocd_notifications_deliveries_total")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
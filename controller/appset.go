@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/argoproj/notifications-engine/pkg/subscriptions"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	appSetKind = "ApplicationSet"
+	// AppSetApplicationsAnnotationPrefix is the prefix of ApplicationSet annotations which subscribe applications
+	// generated by the ApplicationSet, e.g. applications.notifications.argoproj.io/subscribe.on-sync-failed.slack: my-channel
+	AppSetApplicationsAnnotationPrefix = "applications." + subscriptions.AnnotationPrefix
+)
+
+// getAppSet returns the ApplicationSet which generated the given application
+func (c *notificationController) getAppSet(app *unstructured.Unstructured) *unstructured.Unstructured {
+	if c.appSetInformer == nil {
+		return nil
+	}
+	for _, ref := range app.GetOwnerReferences() {
+		if ref.Kind != appSetKind {
+			continue
+		}
+		obj, ok, err := c.appSetInformer.GetIndexer().GetByKey(fmt.Sprintf("%s/%s", app.GetNamespace(), ref.Name))
+		if !ok || err != nil {
+			return nil
+		}
+		appSet, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			return nil
+		}
+		return appSet
+	}
+	return nil
+}
+
+// getAppSetApplicationsAnnotations returns ApplicationSet annotations which subscribe generated applications
+// converted to the regular subscription annotations
+func getAppSetApplicationsAnnotations(appSet *unstructured.Unstructured) subscriptions.Annotations {
+	annotations := map[string]string{}
+	for k, v := range appSet.GetAnnotations() {
+		if strings.HasPrefix(k, AppSetApplicationsAnnotationPrefix+"/") {
+			annotations[subscriptions.AnnotationPrefix+strings.TrimPrefix(k, AppSetApplicationsAnnotationPrefix)] = v
+		}
+	}
+	return subscriptions.NewAnnotations(annotations)
+}
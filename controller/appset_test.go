@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/argoproj/notifications-engine/pkg/api"
+	"github.com/argoproj/notifications-engine/pkg/services"
+	"github.com/argoproj/notifications-engine/pkg/subscriptions"
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/argoproj-labs/argocd-notifications/testing"
+)
+
+func TestAlterAppSetDestinations_IgnoresDefaultTriggers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	appSet := NewAppSet("guestbook", WithAnnotations(map[string]string{
+		subscriptions.SubscribeAnnotationKey("on-appset-error", "slack"): "channel1",
+		"notifications.argoproj.io/subscribe.slack":                      "channel2",
+	}))
+
+	ctrl, _, err := newController(t, ctx, NewFakeClient(appSet), WithApplicationSets(true))
+	assert.NoError(t, err)
+
+	dests := ctrl.alterResourceDestinations(appSet, services.Destinations{}, api.Config{DefaultTriggers: []string{"on-sync-succeeded"}})
+
+	assert.Equal(t, services.Destinations{
+		"on-appset-error": []services.Destination{{Service: "slack", Recipient: "channel1"}},
+	}, dests)
+}
+
+func TestAlterDestinations_AppSetApplicationSubscriptions(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	appSet := NewAppSet("guestbook", WithAnnotations(map[string]string{
+		AppSetApplicationsAnnotationPrefix + "/subscribe.on-sync-failed.slack": "channel1",
+	}))
+	app := NewApp("guestbook-dev", WithOwner("ApplicationSet", "guestbook"))
+
+	ctrl, _, err := newController(t, ctx, NewFakeClient(app, appSet), WithApplicationSets(true))
+	assert.NoError(t, err)
+
+	dests := ctrl.alterDestinations(app, services.Destinations{}, api.Config{})
+
+	assert.Equal(t, services.Destinations{
+		"on-sync-failed": []services.Destination{{Service: "slack", Recipient: "channel1"}},
+	}, dests)
+}
+
+func TestAlterDestinations_AppSetInAnotherShard(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	appSet := NewAppSet("guestbook", WithAnnotations(map[string]string{
+		AppSetApplicationsAnnotationPrefix + "/subscribe.on-sync-failed.slack": "channel1",
+	}))
+	app := NewApp("guestbook-dev", WithOwner("ApplicationSet", "guestbook"))
+	appShard := getShard(fmt.Sprintf("%s/%s", app.GetNamespace(), app.GetName()), 64)
+	appSetShard := getShard(fmt.Sprintf("%s/%s", appSet.GetNamespace(), appSet.GetName()), 64)
+	if !assert.NotEqual(t, appShard, appSetShard) {
+		return
+	}
+
+	ctrl, _, err := newController(t, ctx, NewFakeClient(app, appSet), WithApplicationSets(true), WithSharding(appShard, 64))
+	assert.NoError(t, err)
+
+	dests := ctrl.alterDestinations(app, services.Destinations{}, api.Config{})
+	assert.Equal(t, services.Destinations{
+		"on-sync-failed": []services.Destination{{Service: "slack", Recipient: "channel1"}},
+	}, dests)
+
+	skipped, _ := ctrl.skipOtherShards(appSet)
+	assert.True(t, skipped)
+}
@@ -0,0 +1,173 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/argoproj-labs/argocd-notifications/controller/state"
+	"github.com/argoproj-labs/argocd-notifications/shared/k8s"
+
+	"github.com/argoproj/notifications-engine/pkg/controller"
+	log "github.com/sirupsen/logrus"
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+)
+
+// emptyState is stored instead of removing the state from the state store, so that the outdated state annotation
+// of the resource is not used once the state is cleared
+const emptyState = "{}"
+
+// resourceClient wraps the client used by the notifications-engine controller to persist notifications state
+type resourceClient struct {
+	dynamic.NamespaceableResourceInterface
+	resource string
+	informer cache.SharedIndexInformer
+	ctrl     *notificationController
+}
+
+func (c *resourceClient) Namespace(namespace string) dynamic.ResourceInterface {
+	return &namespacedResourceClient{
+		ResourceInterface: c.NamespaceableResourceInterface.Namespace(namespace),
+		resource:          c.resource,
+		namespace:         namespace,
+		informer:          c.informer,
+		ctrl:              c.ctrl,
+	}
+}
+
+type namespacedResourceClient struct {
+	dynamic.ResourceInterface
+	resource  string
+	namespace string
+	informer  cache.SharedIndexInformer
+	ctrl      *notificationController
+}
+
+func (c *namespacedResourceClient) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, options v1.PatchOptions, subresources ...string) (res *unstructured.Unstructured, err error) {
+	key := fmt.Sprintf("%s/%s/%s", c.resource, c.namespace, name)
+	defer c.ctrl.drainer.persisted(key)
+	ctx, step := c.ctrl.tracer.startStep(key, "PersistState")
+	defer func() {
+		step.end(err)
+	}()
+	if c.ctrl.stateStore != nil {
+		return c.patchState(ctx, name, data)
+	}
+	res, err = c.ResourceInterface.Patch(ctx, name, pt, data, options, subresources...)
+	if err == nil && c.resource == k8s.Applications.Resource && c.ctrl.appPruner != nil {
+		// the patched resource replaces the cached one, so it has to be pruned as well
+		res = c.ctrl.appPruner.prune(res)
+	}
+	return res, err
+}
+
+// patchState saves the notifications state from the annotations patch produced by the notifications-engine
+// controller into the state store and returns the cached resource with the updated state instead of patching it
+func (c *namespacedResourceClient) patchState(ctx context.Context, name string, data []byte) (*unstructured.Unstructured, error) {
+	var patch struct {
+		Metadata struct {
+			Annotations map[string]*string `json:"annotations"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(data, &patch); err != nil {
+		return nil, err
+	}
+	obj, exists, err := c.informer.GetIndexer().GetByKey(fmt.Sprintf("%s/%s", c.namespace, name))
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, apierr.NewNotFound(schema.ParseGroupResource(c.resource), name)
+	}
+	res := obj.(*unstructured.Unstructured).DeepCopy()
+	val, ok := patch.Metadata.Annotations[controller.NotifiedAnnotationKey]
+	if !ok {
+		return res, nil
+	}
+	notifiedState := emptyState
+	if val != nil {
+		notifiedState = *val
+	}
+	if err := c.ctrl.stateStore.Set(ctx, state.Key{Resource: c.resource, Namespace: c.namespace, Name: name}, notifiedState); err != nil {
+		return nil, err
+	}
+	setNotifiedState(res, notifiedState)
+	return res, nil
+}
+
+// stateClient injects the notifications state kept in the state store into resources listed and watched by informers
+type stateClient struct {
+	dynamic.ResourceInterface
+	resource string
+	store    state.Store
+}
+
+func (c *stateClient) List(ctx context.Context, opts v1.ListOptions) (*unstructured.UnstructuredList, error) {
+	list, err := c.ResourceInterface.List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list.Items {
+		c.injectState(ctx, &list.Items[i])
+	}
+	return list, nil
+}
+
+func (c *stateClient) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	w, err := c.ResourceInterface.Watch(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return watch.Filter(w, func(in watch.Event) (watch.Event, bool) {
+		obj, ok := in.Object.(*unstructured.Unstructured)
+		if !ok {
+			return in, true
+		}
+		switch in.Type {
+		case watch.Added, watch.Modified:
+			c.injectState(ctx, obj)
+		case watch.Deleted:
+			if err := c.store.Set(ctx, c.key(obj), ""); err != nil {
+				log.Warnf("Failed to remove notifications state of %s/%s: %v", obj.GetNamespace(), obj.GetName(), err)
+			}
+		}
+		return in, true
+	}), nil
+}
+
+func (c *stateClient) key(obj *unstructured.Unstructured) state.Key {
+	return state.Key{Resource: c.resource, Namespace: obj.GetNamespace(), Name: obj.GetName()}
+}
+
+// injectState replaces the state annotation with the state from the store. The annotation is kept if the store has
+// no state of the resource, so the state stored in annotations before switching to the state store is not lost.
+func (c *stateClient) injectState(ctx context.Context, obj *unstructured.Unstructured) {
+	val, ok, err := c.store.Get(ctx, c.key(obj))
+	if err != nil {
+		log.Warnf("Failed to get notifications state of %s/%s: %v", obj.GetNamespace(), obj.GetName(), err)
+		return
+	}
+	if ok {
+		setNotifiedState(obj, val)
+	}
+}
+
+func setNotifiedState(obj *unstructured.Unstructured, notifiedState string) {
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	if notifiedState == emptyState {
+		delete(annotations, controller.NotifiedAnnotationKey)
+	} else {
+		annotations[controller.NotifiedAnnotationKey] = notifiedState
+	}
+	obj.SetAnnotations(annotations)
+}
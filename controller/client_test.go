@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/argoproj/notifications-engine/pkg/controller"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/argoproj-labs/argocd-notifications/controller/state"
+	"github.com/argoproj-labs/argocd-notifications/shared/k8s"
+	. "github.com/argoproj-labs/argocd-notifications/testing"
+)
+
+func TestStateStore_InjectsState(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	store := state.NewConfigMapStore(ctx, fake.NewSimpleClientset(), state.DefaultConfigMapName)
+	assert.NoError(t, store.Set(ctx, state.Key{Resource: k8s.Applications.Resource, Namespace: TestNamespace, Name: "foo"}, `{"on-deployed":1}`))
+	assert.NoError(t, store.Set(ctx, state.Key{Resource: k8s.Applications.Resource, Namespace: TestNamespace, Name: "bar"}, emptyState))
+
+	ctrl, _, err := newController(t, ctx, NewFakeClient(
+		NewApp("foo"),
+		NewApp("bar", WithAnnotations(map[string]string{controller.NotifiedAnnotationKey: `{"outdated":1}`})),
+		NewApp("baz", WithAnnotations(map[string]string{controller.NotifiedAnnotationKey: `{"on-created":1}`})),
+	), WithStateStore(store))
+	assert.NoError(t, err)
+
+	getState := func(name string) string {
+		obj, exists, err := ctrl.appInformer.GetIndexer().GetByKey(TestNamespace + "/" + name)
+		assert.NoError(t, err)
+		assert.True(t, exists)
+		return obj.(*unstructured.Unstructured).GetAnnotations()[controller.NotifiedAnnotationKey]
+	}
+	assert.Equal(t, `{"on-deployed":1}`, getState("foo"))
+	assert.Equal(t, "", getState("bar"))
+	assert.Equal(t, `{"on-created":1}`, getState("baz"))
+}
+
+func TestStateStore_PatchSavesState(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	store := state.NewConfigMapStore(ctx, fake.NewSimpleClientset(), state.DefaultConfigMapName)
+	client := NewFakeClient(NewApp("foo"))
+	var patches []map[string]interface{}
+	AddPatchCollectorReactor(client, &patches)
+	ctrl, _, err := newController(t, ctx, client, WithStateStore(store))
+	assert.NoError(t, err)
+
+	resClient := &resourceClient{NamespaceableResourceInterface: client.Resource(k8s.Applications), resource: k8s.Applications.Resource, informer: ctrl.appInformer, ctrl: ctrl}
+	app, err := resClient.Namespace(TestNamespace).Patch(ctx, "foo", types.MergePatchType,
+		[]byte(`{"metadata":{"annotations":{"notified.notifications.argoproj.io":"{\"on-deployed\":1}"}}}`), v1.PatchOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"on-deployed":1}`, app.GetAnnotations()[controller.NotifiedAnnotationKey])
+	assert.Empty(t, patches)
+
+	val, ok, err := store.Get(ctx, state.Key{Resource: k8s.Applications.Resource, Namespace: TestNamespace, Name: "foo"})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, `{"on-deployed":1}`, val)
+
+	app, err = resClient.Namespace(TestNamespace).Patch(ctx, "foo", types.MergePatchType,
+		[]byte(`{"metadata":{"annotations":{"notified.notifications.argoproj.io":null}}}`), v1.PatchOptions{})
+	assert.NoError(t, err)
+	assert.NotContains(t, app.GetAnnotations(), controller.NotifiedAnnotationKey)
+	val, _, err = store.Get(ctx, state.Key{Resource: k8s.Applications.Resource, Namespace: TestNamespace, Name: "foo"})
+	assert.NoError(t, err)
+	assert.Equal(t, emptyState, val)
+}
@@ -4,17 +4,28 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/argoproj-labs/argocd-notifications/controller/deadletter"
+	"github.com/argoproj-labs/argocd-notifications/controller/digest"
+	"github.com/argoproj-labs/argocd-notifications/controller/history"
+	"github.com/argoproj-labs/argocd-notifications/controller/state"
 	"github.com/argoproj-labs/argocd-notifications/shared/argocd"
 	"github.com/argoproj-labs/argocd-notifications/shared/k8s"
 	"github.com/argoproj-labs/argocd-notifications/shared/settings"
 
+	"github.com/argoproj/argo-cd/v2/util/glob"
 	"github.com/argoproj/notifications-engine/pkg/api"
 	"github.com/argoproj/notifications-engine/pkg/controller"
 	"github.com/argoproj/notifications-engine/pkg/services"
 	"github.com/argoproj/notifications-engine/pkg/subscriptions"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -22,10 +33,12 @@ import (
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 )
 
 const (
-	resyncPeriod = 60 * time.Second
+	resyncPeriod           = 60 * time.Second
+	defaultShutdownTimeout = 20 * time.Second
 )
 
 type NotificationController interface {
@@ -33,6 +46,109 @@ type NotificationController interface {
 	Init(ctx context.Context) error
 }
 
+type Opts func(ctrl *notificationController)
+
+// WithApplicationNamespaces enables watching applications outside of the Argo CD namespace. Namespaces
+// might be specified using glob patterns.
+func WithApplicationNamespaces(namespaces []string) Opts {
+	return func(ctrl *notificationController) {
+		ctrl.applicationNamespaces = namespaces
+	}
+}
+
+// WithApplicationSets enables notifications about ApplicationSets
+func WithApplicationSets(enabled bool) Opts {
+	return func(ctrl *notificationController) {
+		ctrl.applicationSets = enabled
+	}
+}
+
+// WithEventRecorder enables recording of notification delivery attempts as Kubernetes events
+func WithEventRecorder(recorder record.EventRecorder) Opts {
+	return func(ctrl *notificationController) {
+		ctrl.eventRecorder = recorder
+	}
+}
+
+// WithHistory enables recording of notification delivery attempts in the given history store
+func WithHistory(store *history.Store) Opts {
+	return func(ctrl *notificationController) {
+		ctrl.history = store
+	}
+}
+
+// WithDeadLetters enables storing of notifications that could not be delivered after all retries
+func WithDeadLetters(store *deadletter.Store) Opts {
+	return func(ctrl *notificationController) {
+		ctrl.deadLetters = store
+	}
+}
+
+// WithDigestStore persists buffered digests in the given store
+func WithDigestStore(store *digest.Store) Opts {
+	return func(ctrl *notificationController) {
+		ctrl.digester.store = store
+	}
+}
+
+// WithStateStore keeps notifications state in the given store instead of the resources annotations
+func WithStateStore(store state.Store) Opts {
+	return func(ctrl *notificationController) {
+		ctrl.stateStore = store
+	}
+}
+
+// WithDryRun makes the controller evaluate triggers and render notifications without sending them. Rendered
+// notifications are logged and counted and notifications state is kept in memory only.
+func WithDryRun(enabled bool) Opts {
+	return func(ctrl *notificationController) {
+		if enabled {
+			ctrl.dryRun = newDryRunRecorder(ctrl.registry)
+		}
+	}
+}
+
+// WithMaxSyncStatusRefreshWait limits how long applications are not processed while Argo CD has not refreshed the
+// sync status after the completed operation. Zero means waiting until the sync status is refreshed.
+func WithMaxSyncStatusRefreshWait(wait time.Duration) Opts {
+	return func(ctrl *notificationController) {
+		ctrl.syncStatusWaiter.maxWait = wait
+	}
+}
+
+// WithAppCachePruning makes informers cache only application fields used by the controller and triggers plus the
+// given fields. Templates are rendered using the full application fetched before the rendering.
+func WithAppCachePruning(enabled bool, fields []string) Opts {
+	return func(ctrl *notificationController) {
+		if enabled {
+			ctrl.appPruner = newFieldPruner(fields)
+		}
+	}
+}
+
+// WithSharding configures the controller to handle only applications which key hashes to the given shard
+func WithSharding(shard int, shardCount int) Opts {
+	return func(ctrl *notificationController) {
+		ctrl.shard = shard
+		ctrl.shardCount = shardCount
+	}
+}
+
+// WithShutdownTimeout sets how long Run waits for in-flight notifications after the context is done
+func WithShutdownTimeout(timeout time.Duration) Opts {
+	return func(ctrl *notificationController) {
+		ctrl.shutdownTimeout = timeout
+	}
+}
+
+// WithTracerProvider sets the provider of the tracer which traces resource processing. The global tracer provider is
+// used by default.
+func WithTracerProvider(provider trace.TracerProvider) Opts {
+	return func(ctrl *notificationController) {
+		ctrl.tracer.tracer = provider.Tracer(tracerName)
+	}
+}
+
 func NewController(
 	k8sClient kubernetes.Interface,
 	client dynamic.Interface,
@@ -40,30 +156,85 @@ func NewController(
 	namespace string,
 	appLabelSelector string,
 	registry *controller.MetricsRegistry,
+	opts ...Opts,
 ) *notificationController {
+	res := &notificationController{
+		client:           client,
+		argocdService:    argocdService,
+		registry:         registry,
+		namespace:        namespace,
+		drainer:          newDrainer(),
+		rateLimiter:      newRateLimiter(registry),
+		syncStatusWaiter: newSyncStatusWaiter(registry),
+		tracer:           newProcessingTracer(otel.Tracer(tracerName)),
+		shutdownTimeout:  defaultShutdownTimeout,
+		apiFactories:     map[string]*notificationsAPIFactory{},
+	}
+	res.digester = newDigester(res.getAPI)
+	for i := range opts {
+		opts[i](res)
+	}
+	res.metrics = newProcessingMetrics(registry, res.tracer)
+	res.engineRegistry = registry
+	if res.dryRun != nil {
+		// notifications-engine counts rendered notifications as delivered, so its metrics are not exposed in dry-run
+		// mode and trigger evaluations are counted by the controller instead
+		res.engineRegistry = controller.NewMetricsRegistry("argocd")
+	}
+	if res.dryRun != nil {
+		// the state is kept in memory so that a notification is reported once, as it would be sent once
+		res.stateStore = state.NewMemoryStore(res.stateStore)
+	}
+
 	appClient := client.Resource(k8s.Applications)
-	appInformer := newInformer(appClient.Namespace(namespace), appLabelSelector)
-	appProjInformer := newInformer(k8s.NewAppProjClient(client, namespace), "")
-	secretInformer := k8s.NewSecretInformer(k8sClient, namespace)
-	configMapInformer := k8s.NewConfigMapInformer(k8sClient, namespace)
-	apiFactory := api.NewFactory(settings.GetFactorySettings(argocdService), namespace, secretInformer, configMapInformer)
+	res.appInformer = newInformer(res.newResourceClient(k8s.Applications.Resource, appClient), appLabelSelector, res.getResourceFilter())
+	res.appInformer.AddEventHandler(res.tracer.eventHandler(k8s.Applications.Resource))
+	res.appInformer.AddEventHandler(res.forgetDeletedResources(k8s.Applications.Resource))
+	res.appProjInformer = newInformer(k8s.NewAppProjClient(client, namespace), "", nil)
+	res.secretInformer = k8s.NewSecretInformer(k8sClient, namespace)
+	res.configMapInformer = k8s.NewConfigMapInformer(k8sClient, namespace)
+	appAPIFactory := res.newAPIFactory(k8s.Applications.Resource, settings.GetFactorySettings(argocdService, res.resourceContext(k8s.Applications.Resource)))
+	res.apiFactory = appAPIFactory
 
-	res := &notificationController{
-		secretInformer:    secretInformer,
-		configMapInformer: configMapInformer,
-		appInformer:       appInformer,
-		appProjInformer:   appProjInformer,
-		apiFactory:        apiFactory}
-	res.ctrl = controller.NewController(appClient, appInformer, apiFactory,
-		controller.WithSkipProcessing(func(obj v1.Object) (bool, string) {
+	res.ctrl = controller.NewController(
+		&resourceClient{NamespaceableResourceInterface: appClient, resource: k8s.Applications.Resource, informer: res.appInformer, ctrl: res},
+		res.appInformer,
+		appAPIFactory,
+		controller.WithSkipProcessing(res.skipProcessing(k8s.Applications.Resource, func(obj v1.Object) (bool, string) {
 			app, ok := (obj).(*unstructured.Unstructured)
 			if !ok {
 				return false, ""
 			}
-			return !isAppSyncStatusRefreshed(app, log.WithField("app", obj.GetName())), "sync status out of date"
+			if app.GetNamespace() != namespace && getAppProj(app, res.appProjInformer, namespace) == nil {
+				return true, "application namespace is not permitted by project"
+			}
+			return res.syncStatusWaiter.skip(app, log.WithField("app", obj.GetName()), time.Now()), "sync status out of date"
+		})),
+		controller.WithToUnstructured(func(obj v1.Object) (*unstructured.Unstructured, error) {
+			app, ok := obj.(*unstructured.Unstructured)
+			if !ok {
+				return nil, fmt.Errorf("Object must be *unstructured.Unstructured but was: %v", obj)
+			}
+			return res.syncStatusWaiter.toUnstructured(app), nil
 		}),
-		controller.WithMetricsRegistry(registry),
+		controller.WithMetricsRegistry(res.engineRegistry),
 		controller.WithAlterDestinations(res.alterDestinations))
+
+	if res.applicationSets {
+		appSetClient := client.Resource(k8s.ApplicationSets)
+		// ApplicationSets of all shards are cached since applications are subscribed by the owning ApplicationSet,
+		// which might belong to another shard
+		res.appSetInformer = newInformer(res.newResourceClient(k8s.ApplicationSets.Resource, appSetClient), "", res.getNamespaceFilter())
+		res.appSetInformer.AddEventHandler(res.tracer.eventHandler(k8s.ApplicationSets.Resource))
+		res.appSetInformer.AddEventHandler(res.forgetDeletedResources(k8s.ApplicationSets.Resource))
+		res.appSetCtrl = controller.NewController(
+			&resourceClient{NamespaceableResourceInterface: appSetClient, resource: k8s.ApplicationSets.Resource, informer: res.appSetInformer, ctrl: res},
+			res.appSetInformer,
+			res.newAPIFactory(k8s.ApplicationSets.Resource, settings.GetAppSetFactorySettings(argocdService, res.resourceContext(k8s.ApplicationSets.Resource))),
+			controller.WithSkipProcessing(res.skipProcessing(k8s.ApplicationSets.Resource, res.skipOtherShards)),
+			controller.WithMetricsRegistry(res.engineRegistry),
+			controller.WithAlterDestinations(res.alterResourceDestinations))
+	}
 	return res
 }
 
@@ -73,23 +244,81 @@ func (c *notificationController) alterDestinations(obj v1.Object, destinations s
 		return destinations
 	}
 
-	if proj := getAppProj(app, c.appProjInformer); proj != nil {
+	if proj := getAppProj(app, c.appProjInformer, c.namespace); proj != nil {
 		destinations.Merge(subscriptions.NewAnnotations(proj.GetAnnotations()).GetDestinations(cfg.DefaultTriggers, cfg.ServiceDefaultTriggers))
 		destinations.Merge(settings.GetLegacyDestinations(proj.GetAnnotations(), cfg.DefaultTriggers, cfg.ServiceDefaultTriggers))
 	}
+	if appSet := c.getAppSet(app); appSet != nil {
+		destinations.Merge(getAppSetApplicationsAnnotations(appSet).GetDestinations(cfg.DefaultTriggers, cfg.ServiceDefaultTriggers))
+	}
 	return destinations
 }
 
-func newInformer(resClient dynamic.ResourceInterface, selector string) cache.SharedIndexInformer {
+// newResourceClient returns the client of the resources in the Argo CD namespace or in all namespaces if
+// the controller handles applications in several namespaces
+func (c *notificationController) newResourceClient(resource string, resClient dynamic.NamespaceableResourceInterface) dynamic.ResourceInterface {
+	var res dynamic.ResourceInterface = resClient
+	if len(c.applicationNamespaces) == 0 {
+		res = resClient.Namespace(c.namespace)
+	}
+	if resource == k8s.Applications.Resource && c.appPruner != nil {
+		res = &pruningClient{ResourceInterface: res, pruner: c.appPruner}
+	}
+	if c.stateStore != nil {
+		res = &stateClient{ResourceInterface: res, resource: resource, store: c.stateStore}
+	}
+	return res
+}
+
+// getResourceFilter returns the informer filter that drops resources not handled by this controller instance
+func (c *notificationController) getResourceFilter() func(obj *unstructured.Unstructured) bool {
+	if len(c.applicationNamespaces) > 0 || c.shardCount > 1 {
+		return c.isApplicationHandled
+	}
+	return nil
+}
+
+// getNamespaceFilter returns the informer filter that drops resources outside of namespaces handled by the controller
+func (c *notificationController) getNamespaceFilter() func(obj *unstructured.Unstructured) bool {
+	if len(c.applicationNamespaces) > 0 {
+		return func(obj *unstructured.Unstructured) bool {
+			return c.isApplicationNamespaceAllowed(obj.GetNamespace())
+		}
+	}
+	return nil
+}
+
+func newInformer(resClient dynamic.ResourceInterface, selector string, filter func(obj *unstructured.Unstructured) bool) cache.SharedIndexInformer {
 	informer := cache.NewSharedIndexInformer(
 		&cache.ListWatch{
 			ListFunc: func(options v1.ListOptions) (object runtime.Object, err error) {
 				options.LabelSelector = selector
-				return resClient.List(context.Background(), options)
+				list, err := resClient.List(context.Background(), options)
+				if err != nil || filter == nil {
+					return list, err
+				}
+				var items []unstructured.Unstructured
+				for i := range list.Items {
+					if filter(&list.Items[i]) {
+						items = append(items, list.Items[i])
+					}
+				}
+				list.Items = items
+				return list, nil
 			},
 			WatchFunc: func(options v1.ListOptions) (watch.Interface, error) {
 				options.LabelSelector = selector
-				return resClient.Watch(context.Background(), options)
+				w, err := resClient.Watch(context.Background(), options)
+				if err != nil || filter == nil {
+					return w, err
+				}
+				return watch.Filter(w, func(in watch.Event) (watch.Event, bool) {
+					obj, ok := in.Object.(*unstructured.Unstructured)
+					if !ok || in.Type == watch.Bookmark {
+						return in, true
+					}
+					return in, filter(obj)
+				}), nil
 			},
 		},
 		&unstructured.Unstructured{},
@@ -100,36 +329,247 @@ func newInformer(resClient dynamic.ResourceInterface, selector string) cache.Sha
 }
 
 type notificationController struct {
-	apiFactory        api.Factory
-	ctrl              controller.NotificationController
-	appInformer       cache.SharedIndexInformer
-	appProjInformer   cache.SharedIndexInformer
-	secretInformer    cache.SharedIndexInformer
-	configMapInformer cache.SharedIndexInformer
+	client                dynamic.Interface
+	argocdService         argocd.Service
+	registry              *controller.MetricsRegistry
+	engineRegistry        *controller.MetricsRegistry
+	namespace             string
+	applicationNamespaces []string
+	shard                 int
+	shardCount            int
+	applicationSets       bool
+	apiFactory            api.Factory
+	apiFactories          map[string]*notificationsAPIFactory
+	ctrl                  controller.NotificationController
+	appInformer           cache.SharedIndexInformer
+	appProjInformer       cache.SharedIndexInformer
+	secretInformer        cache.SharedIndexInformer
+	configMapInformer     cache.SharedIndexInformer
+	appSetInformer        cache.SharedIndexInformer
+	appSetCtrl            controller.NotificationController
+	customResources       []*customResourceController
+	customResourcesLock   sync.Mutex
+	eventRecorder         record.EventRecorder
+	history               *history.Store
+	deadLetters           *deadletter.Store
+	rateLimiter           *rateLimiter
+	digester              *digester
+	stateStore            state.Store
+	dryRun                *dryRunRecorder
+	tracer                *processingTracer
+	metrics               *processingMetrics
+	appPruner             *fieldPruner
+	syncStatusWaiter      *syncStatusWaiter
+	lastTriggerRuns       sync.Map
+	drainer               *drainer
+	shutdownTimeout       time.Duration
+	synced                int32
+}
+
+// forgetDeletedResources drops the last trigger runs of deleted resources
+func (c *notificationController) forgetDeletedResources(resource string) cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		DeleteFunc: func(obj interface{}) {
+			if key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj); err == nil {
+				c.lastTriggerRuns.Delete(resource + "/" + key)
+			}
+		},
+	}
 }
 
 func (c *notificationController) Init(ctx context.Context) error {
-	go c.appInformer.Run(ctx.Done())
-	go c.appProjInformer.Run(ctx.Done())
 	go c.secretInformer.Run(ctx.Done())
 	go c.configMapInformer.Run(ctx.Done())
+	if c.appPruner != nil {
+		// triggers have to be loaded before applications are cached, so that fields used by triggers are not pruned
+		if !cache.WaitForCacheSync(ctx.Done(), c.secretInformer.HasSynced, c.configMapInformer.HasSynced) {
+			return errors.New("Timed out waiting for caches to sync")
+		}
+		if _, err := c.apiFactory.GetAPI(); err != nil {
+			log.Warnf("Failed to load notifications configuration, applications are not pruned until it is fixed: %v", err)
+		}
+	}
+	go c.appInformer.Run(ctx.Done())
+	go c.appProjInformer.Run(ctx.Done())
+	hasSynced := []cache.InformerSynced{c.appInformer.HasSynced, c.appProjInformer.HasSynced, c.secretInformer.HasSynced, c.configMapInformer.HasSynced}
+	if c.appSetInformer != nil {
+		go c.appSetInformer.Run(ctx.Done())
+		hasSynced = append(hasSynced, c.appSetInformer.HasSynced)
+	}
+
+	if !cache.WaitForCacheSync(ctx.Done(), c.configMapInformer.HasSynced) {
+		return errors.New("Timed out waiting for caches to sync")
+	}
+	if err := c.initCustomResources(); err != nil {
+		return err
+	}
+	for _, r := range c.customResources {
+		go r.informer.Run(ctx.Done())
+		hasSynced = append(hasSynced, r.informer.HasSynced)
+	}
 
-	if !cache.WaitForCacheSync(ctx.Done(), c.appInformer.HasSynced, c.appProjInformer.HasSynced, c.secretInformer.HasSynced, c.configMapInformer.HasSynced) {
+	if !cache.WaitForCacheSync(ctx.Done(), hasSynced...) {
 		return errors.New("Timed out waiting for caches to sync")
 	}
+	atomic.StoreInt32(&c.synced, 1)
 	return nil
 }
 
+// CheckCaches returns an error if informer caches have not been synced yet
+func (c *notificationController) CheckCaches() error {
+	if atomic.LoadInt32(&c.synced) == 0 {
+		return errors.New("caches are not synced")
+	}
+	return nil
+}
+
+// Informers returns informers of the controller by the watched resource
+func (c *notificationController) Informers() map[string]cache.SharedIndexInformer {
+	res := map[string]cache.SharedIndexInformer{
+		k8s.Applications.Resource: c.appInformer,
+		"appprojects":             c.appProjInformer,
+		"secrets":                 c.secretInformer,
+		"configmaps":              c.configMapInformer,
+	}
+	if c.appSetInformer != nil {
+		res[k8s.ApplicationSets.Resource] = c.appSetInformer
+	}
+	c.customResourcesLock.Lock()
+	defer c.customResourcesLock.Unlock()
+	for _, r := range c.customResources {
+		res[r.resource.GroupVersionResource().GroupResource().String()] = r.informer
+	}
+	return res
+}
+
+// CheckConfig returns an error if the notifications ConfigMap or Secret cannot be parsed
+func (c *notificationController) CheckConfig() error {
+	_, err := c.apiFactory.GetAPI()
+	return err
+}
+
+// Run processes applications until the context is done and then waits for in-flight notifications to be
+// sent and persisted, but no longer than the configured shutdown timeout
 func (c *notificationController) Run(ctx context.Context, processors int) {
+	if c.history != nil {
+		// the history is loaded once the leadership is acquired since the previous leader persists it until it steps down
+		if err := c.history.Load(ctx); err != nil {
+			log.Warnf("Failed to load notifications history: %v", err)
+		}
+	}
+	if err := c.digester.restore(ctx); err != nil {
+		log.Warnf("Failed to restore digests: %v", err)
+	}
+	go c.rateLimiter.run(ctx)
+	go c.digester.run(ctx)
+	go c.tracer.run(ctx)
+	var wg sync.WaitGroup
+	ctrls := []controller.NotificationController{}
+	if c.appSetCtrl != nil {
+		ctrls = append(ctrls, c.appSetCtrl)
+	}
+	for _, r := range c.customResources {
+		ctrls = append(ctrls, r.ctrl)
+	}
+	for i := range ctrls {
+		wg.Add(1)
+		go func(ctrl controller.NotificationController) {
+			defer wg.Done()
+			ctrl.Run(processors, ctx.Done())
+		}(ctrls[i])
+	}
 	c.ctrl.Run(processors, ctx.Done())
+	wg.Wait()
+	log.Infof("Draining in-flight notifications (timeout %v)", c.shutdownTimeout)
+	if c.drainer.drain(c.shutdownTimeout) {
+		log.Info("In-flight notifications drained")
+	}
+	// digests are sent before shutdown since buffered notifications are already considered as sent
+	c.digester.flush(time.Time{})
+	c.digester.persist(context.Background())
 }
 
-func getAppProj(app *unstructured.Unstructured, appProjInformer cache.SharedIndexInformer) *unstructured.Unstructured {
+// skipProcessing returns the function which starts tracing of the resource processing and checks if the processing
+// should be skipped using the given function, if any. Skipped resources are counted by reason.
+func (c *notificationController) skipProcessing(resource string, skip func(obj v1.Object) (bool, string)) func(obj v1.Object) (bool, string) {
+	return func(obj v1.Object) (bool, string) {
+		key, err := cache.MetaNamespaceKeyFunc(obj)
+		if err != nil {
+			return false, ""
+		}
+		key = resource + "/" + key
+		c.tracer.start(resource, key, obj)
+		if skip == nil {
+			return false, ""
+		}
+		_, step := c.tracer.startStep(key, "SkipCheck")
+		skipped, reason := skip(obj)
+		step.SetAttributes(attribute.Bool("skipped", skipped))
+		if skipped {
+			step.SetAttributes(attribute.String("reason", reason))
+		}
+		step.end(nil)
+		if skipped {
+			c.metrics.skipped.WithLabelValues(resource, reason).Inc()
+			c.tracer.finish(key)
+		}
+		return skipped, reason
+	}
+}
+
+// isApplicationHandled checks if the application should be handled by this controller instance
+func (c *notificationController) isApplicationHandled(app *unstructured.Unstructured) bool {
+	if !c.isApplicationNamespaceAllowed(app.GetNamespace()) {
+		return false
+	}
+	return c.isInShard(app)
+}
+
+// skipOtherShards skips processing of resources which are cached by all shards but belong to another shard
+func (c *notificationController) skipOtherShards(obj v1.Object) (bool, string) {
+	if !c.isInShard(obj) {
+		return true, "resource belongs to another shard"
+	}
+	return false, ""
+}
+
+// isInShard checks if the resource belongs to the shard handled by this controller instance
+func (c *notificationController) isInShard(obj v1.Object) bool {
+	if c.shardCount > 1 {
+		return getShard(fmt.Sprintf("%s/%s", obj.GetNamespace(), obj.GetName()), c.shardCount) == c.shard
+	}
+	return true
+}
+
+func getShard(key string, shardCount int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(shardCount))
+}
+
+// isApplicationNamespaceAllowed checks if applications of the given namespace are handled by the controller
+func (c *notificationController) isApplicationNamespaceAllowed(namespace string) bool {
+	return namespace == c.namespace || matchesAny(c.applicationNamespaces, namespace)
+}
+
+// matchesAny returns true if the value matches any of the glob patterns
+func matchesAny(patterns []string, val string) bool {
+	for _, pattern := range patterns {
+		if glob.Match(pattern, val) {
+			return true
+		}
+	}
+	return false
+}
+
+// getAppProj returns the application project from the Argo CD namespace. Returns nil if the project does not
+// exist or does not permit applications from the application namespace.
+func getAppProj(app *unstructured.Unstructured, appProjInformer cache.SharedIndexInformer, namespace string) *unstructured.Unstructured {
 	projName, ok, err := unstructured.NestedString(app.Object, "spec", "project")
 	if !ok || err != nil {
 		return nil
 	}
-	projObj, ok, err := appProjInformer.GetIndexer().GetByKey(fmt.Sprintf("%s/%s", app.GetNamespace(), projName))
+	projObj, ok, err := appProjInformer.GetIndexer().GetByKey(fmt.Sprintf("%s/%s", namespace, projName))
 	if !ok || err != nil {
 		return nil
 	}
@@ -137,6 +577,12 @@ func getAppProj(app *unstructured.Unstructured, appProjInformer cache.SharedInde
 	if !ok {
 		return nil
 	}
+	if app.GetNamespace() != namespace {
+		sourceNamespaces, _, _ := unstructured.NestedStringSlice(proj.Object, "spec", "sourceNamespaces")
+		if !matchesAny(sourceNamespaces, app.GetNamespace()) {
+			return nil
+		}
+	}
 	if proj.GetAnnotations() == nil {
 		proj.SetAnnotations(map[string]string{})
 	}
@@ -2,6 +2,7 @@ package controller
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/argoproj/notifications-engine/pkg/services"
@@ -16,6 +17,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes/fake"
 )
@@ -24,7 +26,7 @@ var (
 	logEntry = logrus.NewEntry(logrus.New())
 )
 
-func newController(t *testing.T, ctx context.Context, client dynamic.Interface) (*notificationController, *mocks.MockAPI, error) {
+func newController(t *testing.T, ctx context.Context, client dynamic.Interface, opts ...Opts) (*notificationController, *mocks.MockAPI, error) {
 	mockCtrl := gomock.NewController(t)
 	go func() {
 		<-ctx.Done()
@@ -33,7 +35,7 @@ func newController(t *testing.T, ctx context.Context, client dynamic.Interface)
 	mockAPI := mocks.NewMockAPI(mockCtrl)
 	mockAPI.EXPECT().GetConfig().Return(api.Config{}).AnyTimes()
 	clientset := fake.NewSimpleClientset()
-	c := NewController(clientset, client, nil, TestNamespace, "", controller.NewMetricsRegistry("argocd"))
+	c := NewController(clientset, client, nil, TestNamespace, "", controller.NewMetricsRegistry("argocd"), opts...)
 	c.apiFactory = &mocks.FakeFactory{Api: mockAPI}
 	err := c.Init(ctx)
 	if err != nil {
@@ -59,6 +61,88 @@ func TestSendsNotificationIfProjectTriggered(t *testing.T) {
 	assert.NotEmpty(t, dests)
 }
 
+func TestSendsNotificationIfProjectTriggered_AppInAnyNamespace(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	appProj := NewProject("default", WithAnnotations(map[string]string{
+		subscriptions.SubscribeAnnotationKey("my-trigger", "mock"): "recipient",
+	}), WithSourceNamespaces("team-*"))
+	app := NewApp("test", WithProject("default"), WithNamespace("team-a"))
+
+	ctrl, _, err := newController(t, ctx, NewFakeClient(app, appProj), WithApplicationNamespaces([]string{"team-*"}))
+	assert.NoError(t, err)
+
+	assert.Len(t, ctrl.appInformer.GetStore().List(), 1)
+	dests := ctrl.alterDestinations(app, services.Destinations{}, api.Config{})
+	assert.NotEmpty(t, dests)
+}
+
+func TestGetAppProj_SourceNamespaceNotPermitted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	appProj := NewProject("default", WithSourceNamespaces("team-b"))
+	app := NewApp("test", WithProject("default"), WithNamespace("team-a"))
+
+	ctrl, _, err := newController(t, ctx, NewFakeClient(app, appProj), WithApplicationNamespaces([]string{"team-*"}))
+	assert.NoError(t, err)
+
+	assert.Nil(t, getAppProj(app, ctrl.appProjInformer, TestNamespace))
+}
+
+func TestApplicationNamespacesFilter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	apps := []runtime.Object{
+		NewApp("argocd-app"),
+		NewApp("team-app", WithNamespace("team-a")),
+		NewApp("other-app", WithNamespace("other")),
+	}
+
+	ctrl, _, err := newController(t, ctx, NewFakeClient(apps...), WithApplicationNamespaces([]string{"team-*"}))
+	assert.NoError(t, err)
+
+	var names []string
+	for _, obj := range ctrl.appInformer.GetStore().List() {
+		names = append(names, obj.(*unstructured.Unstructured).GetName())
+	}
+	assert.ElementsMatch(t, []string{"argocd-app", "team-app"}, names)
+}
+
+func TestSharding(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	var apps []runtime.Object
+	for i := 0; i < 20; i++ {
+		apps = append(apps, NewApp(fmt.Sprintf("app-%d", i)))
+	}
+	client := NewFakeClient(apps...)
+
+	total := 0
+	for shard := 0; shard < 3; shard++ {
+		ctrl, _, err := newController(t, ctx, client, WithSharding(shard, 3))
+		assert.NoError(t, err)
+		for _, obj := range ctrl.appInformer.GetStore().List() {
+			app := obj.(*unstructured.Unstructured)
+			assert.Equal(t, shard, getShard(fmt.Sprintf("%s/%s", app.GetNamespace(), app.GetName()), 3))
+		}
+		total += len(ctrl.appInformer.GetStore().List())
+	}
+	assert.Equal(t, len(apps), total)
+}
+
+func TestForgetDeletedResources(t *testing.T) {
+	ctrl := &notificationController{}
+	ctrl.lastTriggerRuns.Store("applications/default/test", triggerRun{trigger: "on-deployed"})
+	ctrl.lastTriggerRuns.Store("applications/default/other", triggerRun{trigger: "on-deployed"})
+
+	ctrl.forgetDeletedResources("applications").OnDelete(NewApp("test"))
+
+	_, ok := ctrl.lastTriggerRuns.Load("applications/default/test")
+	assert.False(t, ok)
+	_, ok = ctrl.lastTriggerRuns.Load("applications/default/other")
+	assert.True(t, ok)
+}
+
 func TestAppSyncStatusRefreshed(t *testing.T) {
 	for name, tc := range testsAppSyncStatusRefreshed {
 		t.Run(name, func(t *testing.T) {
@@ -0,0 +1,110 @@
+package deadletter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/argoproj/notifications-engine/pkg/services"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/argoproj-labs/argocd-notifications/shared/configmap"
+)
+
+const (
+	DefaultConfigMapName = "argocd-notifications-dead-letters"
+
+	idTimeFormat = "20060102-150405.000000000"
+)
+
+// Entry is a notification which could not be delivered
+type Entry struct {
+	// ID is the key of the entry in the dead-letter ConfigMap. The entries are ordered by ID from the oldest to the most recent one.
+	ID           string                `json:"id"`
+	Time         time.Time             `json:"time"`
+	Resource     string                `json:"resource"`
+	Namespace    string                `json:"namespace"`
+	Name         string                `json:"name"`
+	Trigger      string                `json:"trigger"`
+	Templates    []string              `json:"templates,omitempty"`
+	Destination  services.Destination  `json:"destination"`
+	Notification services.Notification `json:"notification"`
+	Attempts     int                   `json:"attempts"`
+	Error        string                `json:"error,omitempty"`
+}
+
+// Store keeps undelivered notifications in a ConfigMap, one key per notification, so that the controller and
+// the CLI can update the store concurrently.
+type Store struct {
+	clientset kubernetes.Interface
+	namespace string
+	name      string
+	size      int
+}
+
+func NewConfigMapStore(clientset kubernetes.Interface, namespace string, name string, size int) *Store {
+	return &Store{clientset: clientset, namespace: namespace, name: name, size: size}
+}
+
+// Add stores the entry and evicts the oldest entries if the store is full
+func (s *Store) Add(ctx context.Context, entry Entry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+	if entry.ID == "" {
+		entry.ID = fmt.Sprintf("%s-%s", entry.Time.UTC().Format(idTimeFormat), string(uuid.NewUUID())[:8])
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if size := len(entry.ID) + len(data); size > configmap.MaxDataSize {
+		return fmt.Errorf("dead letter %s is too large to be stored: %d bytes", entry.ID, size)
+	}
+	_, err = configmap.Update(ctx, s.clientset, s.namespace, s.name, func(cm *v1.ConfigMap) error {
+		cm.Data[entry.ID] = string(data)
+		ids := configmap.SortedKeys(cm.Data)
+		size := configmap.DataSize(cm.Data)
+		for i := 0; i < len(ids) && (len(ids)-i > s.size || size > configmap.MaxDataSize); i++ {
+			size -= len(ids[i]) + len(cm.Data[ids[i]])
+			delete(cm.Data, ids[i])
+		}
+		return nil
+	})
+	return err
+}
+
+// List returns stored entries starting from the oldest one
+func (s *Store) List(ctx context.Context) ([]Entry, error) {
+	cm, err := configmap.Get(ctx, s.clientset, s.namespace, s.name)
+	if err != nil || cm == nil {
+		return nil, err
+	}
+	var entries []Entry
+	for _, id := range configmap.SortedKeys(cm.Data) {
+		var entry Entry
+		if err := json.Unmarshal([]byte(cm.Data[id]), &entry); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal dead letter %s: %v", id, err)
+		}
+		entry.ID = id
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Remove deletes entries with the given IDs
+func (s *Store) Remove(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := configmap.Update(ctx, s.clientset, s.namespace, s.name, func(cm *v1.ConfigMap) error {
+		for _, id := range ids {
+			delete(cm.Data, id)
+		}
+		return nil
+	})
+	return err
+}
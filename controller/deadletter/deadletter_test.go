@@ -0,0 +1,71 @@
+package deadletter
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/argoproj/notifications-engine/pkg/services"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/argoproj-labs/argocd-notifications/shared/configmap"
+)
+
+func TestAddListRemove(t *testing.T) {
+	ctx := context.TODO()
+	store := NewConfigMapStore(fake.NewSimpleClientset(), "default", DefaultConfigMapName, 2)
+	now := time.Now()
+	for i, name := range []string{"first", "second", "third"} {
+		assert.NoError(t, store.Add(ctx, Entry{
+			Time:         now.Add(time.Duration(i) * time.Second),
+			Name:         name,
+			Destination:  services.Destination{Service: "slack", Recipient: "general"},
+			Notification: services.Notification{Message: "hello " + name},
+		}))
+	}
+
+	entries, err := store.List(ctx)
+	assert.NoError(t, err)
+	if !assert.Len(t, entries, 2) {
+		return
+	}
+	assert.Equal(t, "second", entries[0].Name)
+	assert.Equal(t, "third", entries[1].Name)
+	assert.Equal(t, "hello third", entries[1].Notification.Message)
+
+	assert.NoError(t, store.Remove(ctx, entries[0].ID))
+	entries, err = store.List(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "third", entries[0].Name)
+}
+
+func TestList_NotFound(t *testing.T) {
+	entries, err := NewConfigMapStore(fake.NewSimpleClientset(), "default", DefaultConfigMapName, 2).List(context.TODO())
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestAdd_EvictsOldestEntriesOverSizeLimit(t *testing.T) {
+	ctx := context.TODO()
+	store := NewConfigMapStore(fake.NewSimpleClientset(), "default", DefaultConfigMapName, 10)
+	now := time.Now()
+	for i, name := range []string{"first", "second", "third"} {
+		assert.NoError(t, store.Add(ctx, Entry{
+			Time:         now.Add(time.Duration(i) * time.Second),
+			Name:         name,
+			Notification: services.Notification{Message: strings.Repeat("a", configmap.MaxDataSize/3)},
+		}))
+	}
+	assert.Error(t, store.Add(ctx, Entry{Name: "huge", Notification: services.Notification{Message: strings.Repeat("a", configmap.MaxDataSize)}}))
+
+	entries, err := store.List(ctx)
+	assert.NoError(t, err)
+	if !assert.Len(t, entries, 2) {
+		return
+	}
+	assert.Equal(t, "second", entries[0].Name)
+	assert.Equal(t, "third", entries[1].Name)
+}
@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/argoproj-labs/argocd-notifications/controller/deadletter"
+	"github.com/argoproj-labs/argocd-notifications/controller/history"
+
+	"github.com/argoproj/notifications-engine/pkg/api"
+	"github.com/argoproj/notifications-engine/pkg/services"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// delivery describes a notification delivery attempt
+type delivery struct {
+	// ctx is the context of the send span
+	ctx          context.Context
+	resource     string
+	obj          *unstructured.Unstructured
+	trigger      string
+	conditionKey string
+	templates    []string
+	destination  services.Destination
+	// notification is nil if the notification could not be rendered
+	notification *services.Notification
+	// attempts is the number of delivery attempts, zero if the notification has not been rendered
+	attempts int
+	err      error
+}
+
+func (d delivery) result() string {
+	if d.err != nil {
+		return history.ResultFailed
+	}
+	return history.ResultSent
+}
+
+// recordDelivery records the delivery attempt in events and notifications history and keeps notifications that
+// could not be delivered in the dead-letter store
+func (c *notificationController) recordDelivery(cfg api.Config, d delivery) {
+	c.recordDeliveryEvents(cfg, d)
+	if c.deadLetters != nil && d.err != nil && d.attempts > 0 {
+		err := c.deadLetters.Add(context.Background(), deadletter.Entry{
+			Resource:     d.resource,
+			Namespace:    d.obj.GetNamespace(),
+			Name:         d.obj.GetName(),
+			Trigger:      d.trigger,
+			Templates:    d.templates,
+			Destination:  d.destination,
+			Notification: *d.notification,
+			Attempts:     d.attempts,
+			Error:        d.err.Error(),
+		})
+		if err != nil {
+			log.Errorf("Failed to store undelivered notification to %s: %v", formatDestination(d.destination), err)
+		}
+	}
+	if c.history != nil {
+		entry := history.Entry{
+			Resource:      d.resource,
+			Namespace:     d.obj.GetNamespace(),
+			Name:          d.obj.GetName(),
+			Trigger:       d.trigger,
+			ConditionHash: d.conditionKey,
+			Templates:     d.templates,
+			Destination:   d.destination,
+			Result:        d.result(),
+		}
+		if d.notification != nil {
+			entry.Message = d.notification.Message
+		}
+		if d.err != nil {
+			entry.Error = d.err.Error()
+		}
+		c.history.Add(entry)
+	}
+}
@@ -0,0 +1,267 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/argoproj-labs/argocd-notifications/controller/digest"
+	"github.com/argoproj-labs/argocd-notifications/shared/k8s"
+	"github.com/argoproj-labs/argocd-notifications/shared/settings"
+
+	"github.com/argoproj/notifications-engine/pkg/services"
+	"github.com/robfig/cron"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	digestFlushInterval   = time.Second
+	digestPersistInterval = 30 * time.Second
+)
+
+// digester buffers notifications to the destinations of digests and periodically sends the buffered notifications
+// as a single notification rendered by the digest template. It also buffers notifications muted by quiet windows
+// until the end of the window. Buffers are persisted in the optional store so that buffered notifications are
+// not lost if the controller restarts.
+type digester struct {
+	lock    sync.Mutex
+	buffers map[string]*digestBuffer
+	store   *digest.Store
+	dirty   bool
+	// getAPI returns the API of the resource which buffers restored from the store are sent with
+	getAPI func(resource string) (*notificationsAPI, error)
+}
+
+type digestBuffer struct {
+	digest.Buffer
+	// api is nil if the buffer has been restored from the store and no notification has been added since then
+	api *notificationsAPI
+}
+
+func newDigester(getAPI func(resource string) (*notificationsAPI, error)) *digester {
+	return &digester{buffers: map[string]*digestBuffer{}, getAPI: getAPI}
+}
+
+// nextFlush returns the time of the digest following the given time
+func nextFlush(digest settings.Digest, after time.Time) time.Time {
+	if digest.Schedule != "" {
+		if schedule, err := cron.ParseStandard(digest.Schedule); err == nil {
+			return schedule.Next(after)
+		}
+	}
+	return after.Add(digest.Interval.Duration)
+}
+
+// getDigest returns the digest the notification is added to: the digest referred by the digest annotation of the
+// resource, of its project or of the ApplicationSet which generated it, or the digest that lists the destination
+func (c *notificationController) getDigest(digests settings.Digests, resource string, obj *unstructured.Unstructured, trigger string, dest services.Destination) *settings.Digest {
+	name := settings.GetDigestName(obj.GetAnnotations(), trigger, dest.Service)
+	if name == "" && resource == k8s.Applications.Resource {
+		if proj := getAppProj(obj, c.appProjInformer, c.namespace); proj != nil {
+			name = settings.GetDigestName(proj.GetAnnotations(), trigger, dest.Service)
+		}
+		if appSet := c.getAppSet(obj); name == "" && appSet != nil {
+			name = settings.GetDigestName(getAppSetApplicationsAnnotations(appSet), trigger, dest.Service)
+		}
+	}
+	if name == "" {
+		return digests.Get(dest)
+	}
+	digest := digests.GetByName(name)
+	if digest == nil {
+		log.Warnf("Digest %s of %s/%s is not configured, sending notification to %s right away", name, obj.GetNamespace(), obj.GetName(), formatDestination(dest))
+	}
+	return digest
+}
+
+// addToDigest buffers the notification about the resource until the next digest
+func (d *digester) addToDigest(a *notificationsAPI, digest settings.Digest, dest services.Destination, obj *unstructured.Unstructured, trigger string, notification *services.Notification) {
+	d.add(a, digest.Name, digest.Template, nextFlush(digest, time.Now()), dest, obj, trigger, notification)
+}
+
+// add buffers the notification about the resource triggered by the given trigger. The flush time is used only if
+// the buffer with the given name does not exist yet.
+func (d *digester) add(a *notificationsAPI, name string, template string, flushAt time.Time, dest services.Destination, obj *unstructured.Unstructured, trigger string, notification *services.Notification) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	key := name + "/" + formatDestination(dest)
+	buffer, ok := d.buffers[key]
+	if !ok {
+		buffer = &digestBuffer{Buffer: digest.Buffer{Name: name, Template: template, Resource: a.resource, Destination: dest, FlushAt: flushAt}}
+		d.buffers[key] = buffer
+	}
+	// the most recent API is used to render the digest
+	buffer.api = a
+	buffer.Entries = append(buffer.Entries, digest.Entry{
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+		Trigger:   trigger,
+		Time:      time.Now().UTC(),
+		Message:   notification.Message,
+	})
+	d.dirty = true
+}
+
+// takeBuffers removes and returns buffers that are due at the given time or all buffers if the time is zero
+func (d *digester) takeBuffers(now time.Time) []*digestBuffer {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	var res []*digestBuffer
+	for key, buffer := range d.buffers {
+		if now.IsZero() || !now.Before(buffer.FlushAt) {
+			res = append(res, buffer)
+			delete(d.buffers, key)
+			d.dirty = true
+		}
+	}
+	return res
+}
+
+func (d *digester) flush(now time.Time) {
+	for _, buffer := range d.takeBuffers(now) {
+		if buffer.api == nil {
+			api, err := d.getAPI(buffer.Resource)
+			if err != nil {
+				log.WithField("digest", buffer.Name).Errorf("Failed to send restored digest of %d notification(s): %v", len(buffer.Entries), err)
+				continue
+			}
+			buffer.api = api
+		}
+		buffer.send()
+	}
+}
+
+// run sends due digests and persists buffers until the context is done
+func (d *digester) run(ctx context.Context) {
+	ticker := time.NewTicker(digestFlushInterval)
+	defer ticker.Stop()
+	persistTicker := time.NewTicker(digestPersistInterval)
+	defer persistTicker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			d.flush(now)
+		case <-persistTicker.C:
+			d.persist(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// restore loads persisted buffers. Restored notifications are added before the notifications buffered so far.
+func (d *digester) restore(ctx context.Context) error {
+	if d.store == nil {
+		return nil
+	}
+	buffers, err := d.store.Load(ctx)
+	if err != nil {
+		return err
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for i := range buffers {
+		key := buffers[i].Name + "/" + formatDestination(buffers[i].Destination)
+		if buffer, ok := d.buffers[key]; ok {
+			buffer.Entries = append(buffers[i].Entries, buffer.Entries...)
+			buffer.FlushAt = buffers[i].FlushAt
+		} else {
+			d.buffers[key] = &digestBuffer{Buffer: buffers[i]}
+		}
+	}
+	return nil
+}
+
+// persist saves buffers into the store if they have changed since the last save
+func (d *digester) persist(ctx context.Context) {
+	if d.store == nil {
+		return
+	}
+	d.lock.Lock()
+	if !d.dirty {
+		d.lock.Unlock()
+		return
+	}
+	var buffers []digest.Buffer
+	for _, buffer := range d.buffers {
+		buffers = append(buffers, buffer.Buffer)
+	}
+	d.dirty = false
+	d.lock.Unlock()
+
+	if err := d.store.Save(ctx, buffers); err != nil {
+		log.Warnf("Failed to persist digests: %v", err)
+		d.lock.Lock()
+		d.dirty = true
+		d.lock.Unlock()
+	}
+}
+
+// getDigestResource returns the current state of the resource of the digest entry: the resource is read from the
+// informer cache or from the API server if cached applications are pruned. Only the resource metadata is returned if
+// the resource has been deleted since the notification was buffered.
+func (c *notificationController) getDigestResource(resource string, e digest.Entry) map[string]interface{} {
+	key := e.Name
+	if e.Namespace != "" {
+		key = e.Namespace + "/" + e.Name
+	}
+	if informer, ok := c.Informers()[resource]; ok {
+		if obj, exists, err := informer.GetStore().GetByKey(key); err == nil && exists {
+			res := obj.(*unstructured.Unstructured).DeepCopy().Object
+			if resource != k8s.Applications.Resource || c.appPruner == nil {
+				return res
+			}
+			full, err := c.getFullApp(res)
+			if err != nil {
+				log.Warnf("Failed to get application %s, the digest is rendered using the pruned application: %v", key, err)
+				return res
+			}
+			return full
+		}
+	}
+	return map[string]interface{}{
+		"metadata": map[string]interface{}{"namespace": e.Namespace, "name": e.Name},
+	}
+}
+
+func (b *digestBuffer) render() (*services.Notification, error) {
+	if b.Template == "" {
+		lines := []string{fmt.Sprintf("%d notification(s) were muted by %s:", len(b.Entries), b.Name)}
+		for _, e := range b.Entries {
+			lines = append(lines, fmt.Sprintf("- %s/%s: %s", e.Namespace, e.Name, e.Trigger))
+		}
+		return &services.Notification{Message: strings.Join(lines, "\n")}, nil
+	}
+	var entries []map[string]interface{}
+	for _, e := range b.Entries {
+		entries = append(entries, map[string]interface{}{
+			"app":     b.api.ctrl.getDigestResource(b.Resource, e),
+			"trigger": e.Trigger,
+			"time":    e.Time,
+			"message": e.Message,
+		})
+	}
+	return b.api.templates.FormatNotification(map[string]interface{}{
+		"digest":           b.Name,
+		"entries":          entries,
+		serviceTypeVarName: b.Destination.Service,
+		recipientVarName:   b.Destination.Recipient,
+	}, b.Template)
+}
+
+func (b *digestBuffer) send() {
+	logEntry := log.WithField("digest", b.Name).WithField("destination", formatDestination(b.Destination))
+	notification, err := b.render()
+	if err != nil {
+		logEntry.Errorf("Failed to render digest of %d notification(s): %v", len(b.Entries), err)
+		return
+	}
+	if _, err := b.api.deliver(*notification, b.Destination); err != nil {
+		logEntry.Errorf("Failed to send digest of %d notification(s): %v", len(b.Entries), err)
+		return
+	}
+	logEntry.Infof("Sent digest of %d notification(s)", len(b.Entries))
+}
@@ -0,0 +1,119 @@
+package digest
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/argoproj/notifications-engine/pkg/services"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/argoproj-labs/argocd-notifications/shared/configmap"
+)
+
+const (
+	DefaultConfigMapName = "argocd-notifications-digests"
+
+	buffersKey = "buffers"
+)
+
+// Entry is a notification buffered until the next digest. Only the reference to the resource is buffered: the
+// resource is read again when the digest is rendered.
+type Entry struct {
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	Trigger   string    `json:"trigger"`
+	Time      time.Time `json:"time"`
+	Message   string    `json:"message"`
+}
+
+// Buffer holds notifications buffered until the digest is sent to the destination
+type Buffer struct {
+	Name string `json:"name"`
+	// Template renders the buffered notifications. The default summary is sent if the template is empty.
+	Template    string               `json:"template,omitempty"`
+	Resource    string               `json:"resource"`
+	Destination services.Destination `json:"destination"`
+	FlushAt     time.Time            `json:"flushAt"`
+	Entries     []Entry              `json:"entries"`
+}
+
+// Store persists digest buffers in a ConfigMap so that buffered notifications survive the controller restart
+type Store struct {
+	clientset kubernetes.Interface
+	namespace string
+	name      string
+}
+
+func NewConfigMapStore(clientset kubernetes.Interface, namespace string, name string) *Store {
+	return &Store{clientset: clientset, namespace: namespace, name: name}
+}
+
+// Load returns the persisted buffers
+func (s *Store) Load(ctx context.Context) ([]Buffer, error) {
+	cm, err := configmap.Get(ctx, s.clientset, s.namespace, s.name)
+	if err != nil || cm == nil {
+		return nil, err
+	}
+	var buffers []Buffer
+	if data := cm.Data[buffersKey]; data != "" {
+		if err := json.Unmarshal([]byte(data), &buffers); err != nil {
+			return nil, err
+		}
+	}
+	return buffers, nil
+}
+
+// Save replaces the persisted buffers with the given ones
+func (s *Store) Save(ctx context.Context, buffers []Buffer) error {
+	data, err := marshalBuffers(buffers)
+	if err != nil {
+		return err
+	}
+	_, err = configmap.Update(ctx, s.clientset, s.namespace, s.name, func(cm *v1.ConfigMap) error {
+		cm.Data[buffersKey] = data
+		return nil
+	})
+	return err
+}
+
+// marshalBuffers serializes the buffers dropping the oldest entries of the largest buffers if the buffers do not fit
+// into the ConfigMap. Dropped entries are still sent unless the controller restarts before the digest is sent.
+func marshalBuffers(buffers []Buffer) (string, error) {
+	data, err := json.Marshal(buffers)
+	if err != nil {
+		return "", err
+	}
+	if len(data) <= configmap.MaxDataSize {
+		return string(data), nil
+	}
+	trimmed := make([]Buffer, len(buffers))
+	copy(trimmed, buffers)
+	size, dropped := len(data), 0
+	for size > configmap.MaxDataSize {
+		largest := 0
+		for i := range trimmed {
+			if len(trimmed[i].Entries) > len(trimmed[largest].Entries) {
+				largest = i
+			}
+		}
+		if len(trimmed[largest].Entries) == 0 {
+			break
+		}
+		entry, err := json.Marshal(trimmed[largest].Entries[0])
+		if err != nil {
+			return "", err
+		}
+		// the entry is followed by a comma unless it is the last one
+		size -= len(entry) + 1
+		trimmed[largest].Entries = trimmed[largest].Entries[1:]
+		dropped++
+	}
+	if data, err = json.Marshal(trimmed); err != nil {
+		return "", err
+	}
+	log.Warnf("Digests do not fit into ConfigMap, %d oldest buffered notification(s) are not persisted", dropped)
+	return string(data), nil
+}
@@ -0,0 +1,58 @@
+package digest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/argoproj/notifications-engine/pkg/services"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/argoproj-labs/argocd-notifications/shared/configmap"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	store := NewConfigMapStore(fake.NewSimpleClientset(), "default", DefaultConfigMapName)
+	buffers, err := store.Load(context.TODO())
+	assert.NoError(t, err)
+	assert.Empty(t, buffers)
+
+	flushAt := time.Date(2021, 11, 17, 16, 56, 11, 0, time.UTC)
+	assert.NoError(t, store.Save(context.TODO(), []Buffer{{
+		Name:        "deployments",
+		Resource:    "applications",
+		Destination: services.Destination{Service: "slack", Recipient: "deployments"},
+		FlushAt:     flushAt,
+		Entries:     []Entry{{Namespace: "argocd", Name: "guestbook", Trigger: "on-deployed", Message: "hello"}},
+	}}))
+
+	buffers, err = store.Load(context.TODO())
+	assert.NoError(t, err)
+	if !assert.Len(t, buffers, 1) {
+		return
+	}
+	assert.Equal(t, "deployments", buffers[0].Name)
+	assert.True(t, flushAt.Equal(buffers[0].FlushAt))
+	assert.Equal(t, Entry{Namespace: "argocd", Name: "guestbook", Trigger: "on-deployed", Message: "hello"}, buffers[0].Entries[0])
+}
+
+func TestSave_DropsOldestEntriesOverSizeLimit(t *testing.T) {
+	store := NewConfigMapStore(fake.NewSimpleClientset(), "default", DefaultConfigMapName)
+	message := strings.Repeat("a", configmap.MaxDataSize/4)
+	assert.NoError(t, store.Save(context.TODO(), []Buffer{
+		{Name: "small", Entries: []Entry{{Message: "small"}}},
+		{Name: "large", Entries: []Entry{{Message: "first" + message}, {Message: "second" + message}, {Message: "third" + message}, {Message: "fourth" + message}}},
+	}))
+
+	buffers, err := store.Load(context.TODO())
+	assert.NoError(t, err)
+	if !assert.Len(t, buffers, 2) {
+		return
+	}
+	assert.Len(t, buffers[0].Entries, 1)
+	if assert.Len(t, buffers[1].Entries, 3) {
+		assert.True(t, strings.HasPrefix(buffers[1].Entries[0].Message, "second"))
+	}
+}
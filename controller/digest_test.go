@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/argoproj/notifications-engine/pkg/services"
+	"github.com/argoproj/notifications-engine/pkg/templates"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/argoproj-labs/argocd-notifications/controller/digest"
+	"github.com/argoproj-labs/argocd-notifications/shared/k8s"
+	"github.com/argoproj-labs/argocd-notifications/shared/settings"
+	. "github.com/argoproj-labs/argocd-notifications/testing"
+)
+
+func TestNextFlush(t *testing.T) {
+	now := time.Date(2021, 11, 17, 16, 56, 11, 0, time.UTC)
+
+	assert.Equal(t, now.Add(15*time.Minute), nextFlush(settings.Digest{Interval: metav1.Duration{Duration: 15 * time.Minute}}, now))
+	assert.Equal(t, time.Date(2021, 11, 18, 9, 0, 0, 0, time.UTC), nextFlush(settings.Digest{Schedule: "0 9 * * *"}, now))
+}
+
+func TestSend_AddsNotificationToDigest(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	foo, bar := NewApp("foo"), NewApp("bar")
+	ctrl, mockAPI, err := newController(t, ctx, NewFakeClient(foo, bar))
+	assert.NoError(t, err)
+	svc := &fakeService{}
+	mockAPI.EXPECT().GetNotificationServices().Return(map[string]services.NotificationService{"mock": svc}).AnyTimes()
+
+	notificationsAPI := newTestAPI(t, mockAPI, ctrl)
+	notificationsAPI.templates, err = templates.NewService(map[string]services.Notification{
+		"my-template": {Message: "{{.app.metadata.name}}"},
+		"my-digest":   {Message: "{{.digest}}:{{range .entries}} {{.app.metadata.name}}{{end}}"},
+	})
+	assert.NoError(t, err)
+	dest := services.Destination{Service: "mock", Recipient: "recipient"}
+	notificationsAPI.digests = settings.Digests{{
+		Name:         "deployments",
+		Destinations: []services.Destination{dest},
+		Interval:     metav1.Duration{Duration: time.Minute},
+		Template:     "my-digest",
+	}}
+
+	assert.NoError(t, notificationsAPI.Send(foo.Object, []string{"my-template"}, dest))
+	assert.NoError(t, notificationsAPI.Send(bar.Object, []string{"my-template"}, dest))
+	assert.NoError(t, notificationsAPI.Send(foo.Object, []string{"my-template"}, services.Destination{Service: "mock", Recipient: "other"}))
+	assert.Equal(t, []services.Notification{{Message: "foo"}}, svc.sent)
+
+	ctrl.digester.flush(time.Now())
+	assert.Len(t, svc.sent, 1)
+
+	ctrl.digester.flush(time.Now().Add(time.Minute))
+	assert.Equal(t, []services.Notification{{Message: "foo"}, {Message: "deployments: foo bar"}}, svc.sent)
+	assert.Empty(t, ctrl.digester.buffers)
+}
+
+func TestSend_AddsNotificationToSubscriptionDigest(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	foo := NewApp("foo", WithAnnotations(map[string]string{"notifications.argoproj.io/digest.mock": "deployments"}))
+	bar := NewApp("bar", WithAnnotations(map[string]string{"notifications.argoproj.io/digest.mock": "unknown"}))
+	ctrl, mockAPI, err := newController(t, ctx, NewFakeClient(foo, bar))
+	assert.NoError(t, err)
+	svc := &fakeService{}
+	mockAPI.EXPECT().GetNotificationServices().Return(map[string]services.NotificationService{"mock": svc}).AnyTimes()
+
+	notificationsAPI := newTestAPI(t, mockAPI, ctrl)
+	notificationsAPI.digests = settings.Digests{{
+		Name:     "deployments",
+		Interval: metav1.Duration{Duration: time.Minute},
+		Template: "my-template",
+	}}
+	dest := services.Destination{Service: "mock", Recipient: "recipient"}
+
+	assert.NoError(t, notificationsAPI.Send(foo.Object, []string{"my-template"}, dest))
+	assert.NoError(t, notificationsAPI.Send(bar.Object, []string{"my-template"}, dest))
+	assert.Equal(t, []services.Notification{{Message: "bar sent to recipient"}}, svc.sent)
+	assert.Len(t, ctrl.digester.buffers, 1)
+}
+
+func TestDigester_RestoresPersistedBuffers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	app := NewApp("foo")
+	store := digest.NewConfigMapStore(fake.NewSimpleClientset(), TestNamespace, digest.DefaultConfigMapName)
+	ctrl, mockAPI, err := newController(t, ctx, NewFakeClient(app), WithDigestStore(store))
+	assert.NoError(t, err)
+	svc := &fakeService{}
+	mockAPI.EXPECT().GetNotificationServices().Return(map[string]services.NotificationService{"mock": svc}).AnyTimes()
+	api := newTestAPI(t, mockAPI, ctrl)
+	api.templates, err = templates.NewService(map[string]services.Notification{
+		"my-template": {Message: "{{.app.metadata.name}}"},
+		"my-digest":   {Message: "{{.digest}}:{{range .entries}} {{.app.metadata.name}}{{end}}"},
+	})
+	assert.NoError(t, err)
+	dest := services.Destination{Service: "mock", Recipient: "recipient"}
+	api.digests = settings.Digests{{
+		Name:         "deployments",
+		Destinations: []services.Destination{dest},
+		Interval:     metav1.Duration{Duration: time.Minute},
+		Template:     "my-digest",
+	}}
+	assert.NoError(t, api.Send(app.Object, []string{"my-template"}, dest))
+	ctrl.digester.persist(ctx)
+
+	restored := newDigester(func(resource string) (*notificationsAPI, error) {
+		assert.Equal(t, k8s.Applications.Resource, resource)
+		return api, nil
+	})
+	restored.store = store
+	assert.NoError(t, restored.restore(ctx))
+	restored.flush(time.Now().Add(time.Minute))
+	assert.Equal(t, []services.Notification{{Message: "deployments: foo"}}, svc.sent)
+}
+
+func TestDigester_RendersCurrentResources(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	app := NewApp("foo", WithHealthStatus("Progressing"))
+	ctrl, mockAPI, err := newController(t, ctx, NewFakeClient(app))
+	assert.NoError(t, err)
+	svc := &fakeService{}
+	mockAPI.EXPECT().GetNotificationServices().Return(map[string]services.NotificationService{"mock": svc}).AnyTimes()
+	api := newTestAPI(t, mockAPI, ctrl)
+	api.templates, err = templates.NewService(map[string]services.Notification{
+		"my-template": {Message: "{{.app.metadata.name}}"},
+		"my-digest":   {Message: "{{range .entries}}{{.app.metadata.name}}:{{with .app.status}}{{.health.status}}{{end}} {{end}}"},
+	})
+	assert.NoError(t, err)
+	dest := services.Destination{Service: "mock", Recipient: "recipient"}
+	api.digests = settings.Digests{{
+		Name:         "deployments",
+		Destinations: []services.Destination{dest},
+		Interval:     metav1.Duration{Duration: time.Minute},
+		Template:     "my-digest",
+	}}
+	assert.NoError(t, api.Send(app.Object, []string{"my-template"}, dest))
+	assert.NoError(t, api.Send(NewApp("deleted").Object, []string{"my-template"}, dest))
+	assert.NoError(t, ctrl.appInformer.GetStore().Update(NewApp("foo", WithHealthStatus("Healthy"))))
+
+	ctrl.digester.flush(time.Now().Add(time.Minute))
+	assert.Equal(t, []services.Notification{{Message: "foo:Healthy deleted: "}}, svc.sent)
+}
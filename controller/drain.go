@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	drainPollInterval = 100 * time.Millisecond
+)
+
+var errShuttingDown = errors.New("controller is shutting down")
+
+// drainer tracks notifications that are being sent and resources which notifications state has not been persisted yet.
+// Once draining is started no new notifications are sent: such notifications are not marked as sent and are going
+// to be delivered by the next controller instance.
+type drainer struct {
+	lock     sync.Mutex
+	draining bool
+	sending  int
+	pending  map[string]bool
+	stopped  chan struct{}
+}
+
+func newDrainer() *drainer {
+	return &drainer{pending: map[string]bool{}, stopped: make(chan struct{})}
+}
+
+// stopping returns the channel which is closed once draining is started
+func (d *drainer) stopping() <-chan struct{} {
+	return d.stopped
+}
+
+func (d *drainer) startSend() bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.draining {
+		return false
+	}
+	d.sending++
+	return true
+}
+
+func (d *drainer) finishSend(key string, sent bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.sending--
+	if sent {
+		d.pending[key] = true
+	}
+}
+
+// markSent marks the notifications state of the resource as not persisted while the notification is still being sent
+func (d *drainer) markSent(key string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.pending[key] = true
+}
+
+func (d *drainer) persisted(key string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	delete(d.pending, key)
+}
+
+func (d *drainer) idle() bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.sending == 0 && len(d.pending) == 0
+}
+
+// drain stops accepting new notifications and waits until in-flight notifications are sent and persisted
+func (d *drainer) drain(timeout time.Duration) bool {
+	d.lock.Lock()
+	if !d.draining {
+		d.draining = true
+		close(d.stopped)
+	}
+	d.lock.Unlock()
+
+	deadline := time.Now().Add(timeout)
+	for !d.idle() {
+		if time.Now().After(deadline) {
+			d.lock.Lock()
+			log.Warnf("Timed out draining notifications: %d being sent, %d resources not persisted", d.sending, len(d.pending))
+			d.lock.Unlock()
+			return false
+		}
+		time.Sleep(drainPollInterval)
+	}
+	return true
+}
@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrain_WaitsForPersistedState(t *testing.T) {
+	d := newDrainer()
+	assert.True(t, d.startSend())
+	d.finishSend("default/guestbook", true)
+
+	go func() {
+		time.Sleep(2 * drainPollInterval)
+		d.persisted("default/guestbook")
+	}()
+
+	assert.True(t, d.drain(time.Second))
+	assert.False(t, d.startSend())
+}
+
+func TestDrain_FailedSendIsNotPending(t *testing.T) {
+	d := newDrainer()
+	assert.True(t, d.startSend())
+	d.finishSend("default/guestbook", false)
+
+	assert.True(t, d.idle())
+}
+
+func TestDrain_Timeout(t *testing.T) {
+	d := newDrainer()
+	assert.True(t, d.startSend())
+
+	assert.False(t, d.drain(drainPollInterval))
+}
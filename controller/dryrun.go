@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/argoproj/notifications-engine/pkg/controller"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// dryRunRecorder logs and counts notifications which would have been sent if the controller was not in dry-run mode
+type dryRunRecorder struct {
+	counter *prometheus.CounterVec
+}
+
+func newDryRunRecorder(registry *controller.MetricsRegistry) *dryRunRecorder {
+	counter := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "argocd_notifications_dry_run_total",
+			Help: "Number of notifications rendered but not sent in dry-run mode.",
+		},
+		[]string{"resource", "trigger", "service", "rendered"},
+	)
+	if registry != nil {
+		registry.MustRegister(counter)
+	}
+	return &dryRunRecorder{counter: counter}
+}
+
+func (r *dryRunRecorder) record(d delivery) {
+	r.counter.WithLabelValues(d.resource, d.trigger, d.destination.Service, strconv.FormatBool(d.err == nil)).Inc()
+	logEntry := log.WithFields(log.Fields{
+		"resource":    d.resource,
+		"namespace":   d.obj.GetNamespace(),
+		"name":        d.obj.GetName(),
+		"trigger":     d.trigger,
+		"templates":   d.templates,
+		"destination": formatDestination(d.destination),
+	})
+	if d.err != nil {
+		logEntry.Warnf("Dry run: failed to render notification: %v", d.err)
+		return
+	}
+	logEntry.Infof("Dry run: notification is not sent: %s", d.notification.Message)
+}
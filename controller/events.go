@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/argoproj-labs/argocd-notifications/shared/k8s"
+	"github.com/argoproj-labs/argocd-notifications/shared/settings"
+
+	"github.com/argoproj/notifications-engine/pkg/api"
+	"github.com/argoproj/notifications-engine/pkg/services"
+	"github.com/argoproj/notifications-engine/pkg/subscriptions"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	EventReasonNotificationSent   = "NotificationSent"
+	EventReasonNotificationFailed = "NotificationFailed"
+)
+
+// recordDeliveryEvents records the notification delivery attempt as an event of the resource and, if the
+// destination comes from the project subscriptions, as an event of the application project
+func (c *notificationController) recordDeliveryEvents(cfg api.Config, d delivery) {
+	if c.eventRecorder == nil {
+		return
+	}
+	templates := strings.Join(d.templates, ",")
+	eventType, reason := v1.EventTypeNormal, EventReasonNotificationSent
+	message := fmt.Sprintf("Sent notification about trigger '%s' using template(s) '%s' to %s", d.trigger, templates, formatDestination(d.destination))
+	if d.err != nil {
+		eventType, reason = v1.EventTypeWarning, EventReasonNotificationFailed
+		message = fmt.Sprintf("Failed to send notification about trigger '%s' using template(s) '%s' to %s: %v", d.trigger, templates, formatDestination(d.destination), d.err)
+	}
+
+	c.eventRecorder.Event(d.obj, eventType, reason, message)
+
+	if d.resource != k8s.Applications.Resource {
+		return
+	}
+	if proj := getAppProj(d.obj, c.appProjInformer, c.namespace); proj != nil && hasProjectDestination(proj, cfg, d.trigger, d.destination) {
+		c.eventRecorder.Event(proj, eventType, reason, fmt.Sprintf("Application %s/%s: %s", d.obj.GetNamespace(), d.obj.GetName(), message))
+	}
+}
+
+func hasProjectDestination(proj *unstructured.Unstructured, cfg api.Config, trigger string, dest services.Destination) bool {
+	destinations := subscriptions.NewAnnotations(proj.GetAnnotations()).GetDestinations(cfg.DefaultTriggers, cfg.ServiceDefaultTriggers)
+	destinations.Merge(settings.GetLegacyDestinations(proj.GetAnnotations(), cfg.DefaultTriggers, cfg.ServiceDefaultTriggers))
+	for _, d := range destinations[trigger] {
+		if d == dest {
+			return true
+		}
+	}
+	return false
+}
+
+func formatDestination(dest services.Destination) string {
+	if dest.Recipient == "" {
+		return dest.Service
+	}
+	return fmt.Sprintf("%s:%s", dest.Service, dest.Recipient)
+}
@@ -0,0 +1,44 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/argoproj/notifications-engine/pkg/services"
+	"github.com/argoproj/notifications-engine/pkg/subscriptions"
+	"github.com/argoproj/notifications-engine/pkg/triggers"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/tools/record"
+
+	. "github.com/argoproj-labs/argocd-notifications/testing"
+)
+
+func TestSend_RecordsEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	appProj := NewProject("default", WithAnnotations(map[string]string{
+		subscriptions.SubscribeAnnotationKey("my-trigger", "mock"): "recipient",
+	}))
+	app := NewApp("test", WithProject("default"))
+	recorder := record.NewFakeRecorder(10)
+
+	ctrl, mockAPI, err := newController(t, ctx, NewFakeClient(app, appProj), WithEventRecorder(recorder))
+	assert.NoError(t, err)
+	mockAPI.EXPECT().RunTrigger("my-trigger", gomock.Any()).Return([]triggers.ConditionResult{{Triggered: true}}, nil)
+	mockAPI.EXPECT().GetNotificationServices().Return(map[string]services.NotificationService{
+		"mock": &fakeService{errors: map[string]error{"other": errors.New("boom")}},
+	}).AnyTimes()
+
+	notificationsAPI := newTestAPI(t, mockAPI, ctrl)
+	_, err = notificationsAPI.RunTrigger("my-trigger", app.Object)
+	assert.NoError(t, err)
+	assert.NoError(t, notificationsAPI.Send(app.Object, []string{"my-template"}, services.Destination{Service: "mock", Recipient: "recipient"}))
+	assert.Error(t, notificationsAPI.Send(app.Object, []string{"my-template"}, services.Destination{Service: "mock", Recipient: "other"}))
+
+	assert.Equal(t, "Normal NotificationSent Sent notification about trigger 'my-trigger' using template(s) 'my-template' to mock:recipient", <-recorder.Events)
+	assert.Equal(t, "Normal NotificationSent Application default/test: Sent notification about trigger 'my-trigger' using template(s) 'my-template' to mock:recipient", <-recorder.Events)
+	assert.Equal(t, "Warning NotificationFailed Failed to send notification about trigger 'my-trigger' using template(s) 'my-template' to mock:other: boom", <-recorder.Events)
+	assert.Empty(t, recorder.Events)
+}
@@ -0,0 +1,251 @@
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/argoproj/notifications-engine/pkg/services"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/argoproj-labs/argocd-notifications/shared/configmap"
+)
+
+const (
+	ResultSent   = "sent"
+	ResultFailed = "failed"
+
+	historyKey = "history"
+	// maxMessageLength limits the stored message size so that the history fits into the ConfigMap
+	maxMessageLength = 1024
+)
+
+// Entry describes a notification delivery attempt
+type Entry struct {
+	Time          time.Time            `json:"time"`
+	Resource      string               `json:"resource"`
+	Namespace     string               `json:"namespace"`
+	Name          string               `json:"name"`
+	Trigger       string               `json:"trigger"`
+	ConditionHash string               `json:"conditionHash,omitempty"`
+	Templates     []string             `json:"templates,omitempty"`
+	Destination   services.Destination `json:"destination"`
+	Message       string               `json:"message,omitempty"`
+	Result        string               `json:"result"`
+	Error         string               `json:"error,omitempty"`
+}
+
+// Store is the bounded history of notification delivery attempts persisted in a ConfigMap
+type Store struct {
+	clientset kubernetes.Interface
+	namespace string
+	name      string
+	size      int
+
+	lock    sync.Mutex
+	entries []Entry
+	dirty   bool
+}
+
+func NewConfigMapStore(clientset kubernetes.Interface, namespace string, name string, size int) *Store {
+	return &Store{clientset: clientset, namespace: namespace, name: name, size: size}
+}
+
+// Add appends the entry and evicts the oldest entries if the history is full
+func (s *Store) Add(entry Entry) {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+	entry.Message = truncate(entry.Message, maxMessageLength)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.entries = append(s.entries, entry)
+	if cnt := len(s.entries) - s.size; cnt > 0 {
+		s.entries = append([]Entry(nil), s.entries[cnt:]...)
+	}
+	s.dirty = true
+}
+
+// truncate cuts the string to at most the given number of bytes without splitting multi-byte characters
+func truncate(s string, length int) string {
+	if len(s) <= length {
+		return s
+	}
+	for length > 0 && !utf8.RuneStart(s[length]) {
+		length--
+	}
+	return s[:length]
+}
+
+// key identifies the delivery attempt so that the same entry is kept once when entries are merged
+func (e Entry) key() string {
+	return strings.Join([]string{e.Time.UTC().Format(time.RFC3339Nano), e.Resource, e.Namespace, e.Name, e.Trigger, e.Destination.Service, e.Destination.Recipient}, "/")
+}
+
+// mergeEntries returns the most recent entries of both lists ordered by time without duplicates
+func mergeEntries(a []Entry, b []Entry, size int) []Entry {
+	seen := map[string]bool{}
+	var res []Entry
+	for _, entries := range [][]Entry{a, b} {
+		for _, e := range entries {
+			if key := e.key(); !seen[key] {
+				seen[key] = true
+				res = append(res, e)
+			}
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Time.Before(res[j].Time)
+	})
+	if cnt := len(res) - size; cnt > 0 {
+		res = res[cnt:]
+	}
+	return res
+}
+
+// Filter selects history entries. Empty fields match any value.
+type Filter struct {
+	Namespace string
+	Name      string
+	Trigger   string
+	Result    string
+	Limit     int
+}
+
+func (f Filter) matches(e Entry) bool {
+	return (f.Namespace == "" || f.Namespace == e.Namespace) &&
+		(f.Name == "" || f.Name == e.Name) &&
+		(f.Trigger == "" || f.Trigger == e.Trigger) &&
+		(f.Result == "" || f.Result == e.Result)
+}
+
+// List returns matching entries starting from the most recent one
+func (s *Store) List(filter Filter) []Entry {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	res := []Entry{}
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		if filter.Limit > 0 && len(res) >= filter.Limit {
+			break
+		}
+		if filter.matches(s.entries[i]) {
+			res = append(res, s.entries[i])
+		}
+	}
+	return res
+}
+
+// ServeHTTP responds with the JSON list of entries filtered by the namespace, name, trigger, result and limit query parameters
+func (s *Store) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := Filter{
+		Namespace: query.Get("namespace"),
+		Name:      query.Get("name"),
+		Trigger:   query.Get("trigger"),
+		Result:    query.Get("result"),
+	}
+	if limit := query.Get("limit"); limit != "" {
+		val, err := strconv.Atoi(limit)
+		if err != nil {
+			http.Error(w, "limit must be a number", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = val
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(s.List(filter))
+}
+
+// Load restores the history from the ConfigMap. The loaded entries are merged with the entries added so far.
+func (s *Store) Load(ctx context.Context) error {
+	cm, err := configmap.Get(ctx, s.clientset, s.namespace, s.name)
+	if err != nil || cm == nil {
+		return err
+	}
+	entries := parseEntries(cm)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.entries = mergeEntries(entries, s.entries, s.size)
+	return nil
+}
+
+func parseEntries(cm *v1.ConfigMap) []Entry {
+	var entries []Entry
+	if data := cm.Data[historyKey]; data != "" {
+		if err := json.Unmarshal([]byte(data), &entries); err != nil {
+			log.Warnf("Failed to parse notifications history, ignoring the persisted history: %v", err)
+			return nil
+		}
+	}
+	return entries
+}
+
+// Run periodically persists the history until the context is done
+func (s *Store) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.Persist(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Persist saves the history into the ConfigMap if it has changed since the last save
+func (s *Store) Persist(ctx context.Context) {
+	s.lock.Lock()
+	if !s.dirty {
+		s.lock.Unlock()
+		return
+	}
+	entries := s.entries
+	s.dirty = false
+	s.lock.Unlock()
+
+	if err := s.save(ctx, entries); err != nil {
+		log.Warnf("Failed to persist notifications history: %v", err)
+		s.lock.Lock()
+		s.dirty = true
+		s.lock.Unlock()
+	}
+}
+
+// save merges the entries with the persisted ones, so that entries persisted by another controller instance, e.g. the
+// previous leader, are not overwritten
+func (s *Store) save(ctx context.Context, entries []Entry) error {
+	_, err := configmap.Update(ctx, s.clientset, s.namespace, s.name, func(cm *v1.ConfigMap) error {
+		data, err := marshalEntries(mergeEntries(parseEntries(cm), entries, s.size))
+		if err != nil {
+			return err
+		}
+		cm.Data[historyKey] = data
+		return nil
+	})
+	return err
+}
+
+// marshalEntries serializes the entries dropping the oldest ones if the history does not fit into the ConfigMap
+func marshalEntries(entries []Entry) (string, error) {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return "", err
+	}
+	for len(data) > configmap.MaxDataSize && len(entries) > 0 {
+		entries = entries[len(entries)/10+1:]
+		if data, err = json.Marshal(entries); err != nil {
+			return "", err
+		}
+	}
+	return string(data), nil
+}
@@ -0,0 +1,105 @@
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestAdd_EvictsOldestEntries(t *testing.T) {
+	store := NewConfigMapStore(fake.NewSimpleClientset(), "default", "history", 2)
+	store.Add(Entry{Name: "first"})
+	store.Add(Entry{Name: "second"})
+	store.Add(Entry{Name: "third", Message: strings.Repeat("a", maxMessageLength+1)})
+
+	entries := store.List(Filter{})
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "third", entries[0].Name)
+	assert.Len(t, entries[0].Message, maxMessageLength)
+	assert.False(t, entries[0].Time.IsZero())
+	assert.Equal(t, "second", entries[1].Name)
+}
+
+func TestList_Filter(t *testing.T) {
+	store := NewConfigMapStore(fake.NewSimpleClientset(), "default", "history", 10)
+	store.Add(Entry{Namespace: "default", Name: "foo", Trigger: "on-sync-failed", Result: ResultSent})
+	store.Add(Entry{Namespace: "default", Name: "bar", Trigger: "on-sync-failed", Result: ResultFailed})
+	store.Add(Entry{Namespace: "default", Name: "foo", Trigger: "on-deployed", Result: ResultSent})
+
+	assert.Len(t, store.List(Filter{Name: "foo"}), 2)
+	assert.Len(t, store.List(Filter{Trigger: "on-sync-failed", Result: ResultFailed}), 1)
+	assert.Len(t, store.List(Filter{Namespace: "other"}), 0)
+	entries := store.List(Filter{Limit: 1})
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "on-deployed", entries[0].Trigger)
+}
+
+func TestServeHTTP(t *testing.T) {
+	store := NewConfigMapStore(fake.NewSimpleClientset(), "default", "history", 10)
+	store.Add(Entry{Name: "foo"})
+	store.Add(Entry{Name: "bar"})
+
+	w := httptest.NewRecorder()
+	store.ServeHTTP(w, httptest.NewRequest("GET", "/history?name=bar", nil))
+
+	var entries []Entry
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &entries))
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "bar", entries[0].Name)
+
+	w = httptest.NewRecorder()
+	store.ServeHTTP(w, httptest.NewRequest("GET", "/history?limit=abc", nil))
+	assert.Equal(t, 400, w.Code)
+}
+
+func TestPersistAndLoad(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	store := NewConfigMapStore(clientset, "default", "history", 10)
+	store.Add(Entry{Name: "foo"})
+	store.Persist(context.TODO())
+	store.Add(Entry{Name: "bar"})
+	store.Persist(context.TODO())
+
+	cm, err := clientset.CoreV1().ConfigMaps("default").Get(context.TODO(), "history", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Contains(t, cm.Data[historyKey], "bar")
+
+	restored := NewConfigMapStore(clientset, "default", "history", 10)
+	assert.NoError(t, restored.Load(context.TODO()))
+	entries := restored.List(Filter{})
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "bar", entries[0].Name)
+}
+
+func TestAdd_TruncatesMessageOnRuneBoundary(t *testing.T) {
+	store := NewConfigMapStore(fake.NewSimpleClientset(), "default", "history", 2)
+	store.Add(Entry{Name: "foo", Message: "a" + strings.Repeat("ü", maxMessageLength)})
+
+	message := store.List(Filter{})[0].Message
+	assert.True(t, utf8.ValidString(message))
+	assert.Len(t, message, maxMessageLength-1)
+}
+
+func TestPersist_MergesPersistedEntries(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	previous := NewConfigMapStore(clientset, "default", "history", 10)
+	current := NewConfigMapStore(clientset, "default", "history", 10)
+	current.Add(Entry{Name: "bar"})
+	previous.Add(Entry{Name: "foo"})
+	current.Persist(context.TODO())
+	previous.Persist(context.TODO())
+
+	restored := NewConfigMapStore(clientset, "default", "history", 10)
+	assert.NoError(t, restored.Load(context.TODO()))
+	entries := restored.List(Filter{})
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "foo", entries[0].Name)
+	assert.Equal(t, "bar", entries[1].Name)
+}
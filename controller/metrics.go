@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"github.com/argoproj/notifications-engine/pkg/controller"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// processingMetrics exposes metrics of the resources processing which are not provided by the notifications-engine
+type processingMetrics struct {
+	skipped    *prometheus.CounterVec
+	queueDepth *queueDepthCollector
+}
+
+func newProcessingMetrics(registry *controller.MetricsRegistry, tracer *processingTracer) *processingMetrics {
+	skipped := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "argocd_notifications_processing_skipped_total",
+			Help: "Number of times resources processing was skipped.",
+		},
+		[]string{"resource", "reason"},
+	)
+	queueDepth := &queueDepthCollector{
+		tracer: tracer,
+		desc: prometheus.NewDesc(
+			"argocd_notifications_queue_depth",
+			"Number of resources waiting to be processed.",
+			[]string{"resource"}, nil),
+	}
+	if registry != nil {
+		registry.MustRegister(skipped, queueDepth)
+	}
+	return &processingMetrics{skipped: skipped, queueDepth: queueDepth}
+}
+
+// queueDepthCollector reports the number of resources which informer events have not been processed yet
+type queueDepthCollector struct {
+	tracer *processingTracer
+	desc   *prometheus.Desc
+}
+
+func (c *queueDepthCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *queueDepthCollector) Collect(ch chan<- prometheus.Metric) {
+	for resource, count := range c.tracer.pendingCounts() {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(count), resource)
+	}
+}
@@ -0,0 +1,35 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/argoproj-labs/argocd-notifications/shared/k8s"
+	. "github.com/argoproj-labs/argocd-notifications/testing"
+)
+
+func TestProcessingMetrics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	app := NewApp("test")
+	ctrl, _, err := newController(t, ctx, NewFakeClient(app))
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return testutil.CollectAndCount(ctrl.metrics.queueDepth) == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, map[string]int{k8s.Applications.Resource: 1}, ctrl.tracer.pendingCounts())
+
+	skipped, reason := ctrl.skipProcessing(k8s.Applications.Resource, func(obj v1.Object) (bool, string) {
+		return true, "sync status out of date"
+	})(app)
+	assert.True(t, skipped)
+	assert.Equal(t, "sync status out of date", reason)
+	assert.Empty(t, ctrl.tracer.pendingCounts())
+	assert.Equal(t, float64(1), testutil.ToFloat64(ctrl.metrics.skipped.WithLabelValues(k8s.Applications.Resource, "sync status out of date")))
+}
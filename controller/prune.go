@@ -0,0 +1,157 @@
+package controller
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/argoproj-labs/argocd-notifications/shared/k8s"
+
+	"github.com/argoproj/notifications-engine/pkg/triggers"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	lastAppliedConfigAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+)
+
+var (
+	// requiredAppFields are application fields used by the controller itself and by expression helpers
+	requiredAppFields = []string{
+		"metadata",
+		"operation",
+		"spec.project",
+		"spec.destination",
+		"spec.source",
+		"spec.sources",
+		"status.operationState",
+		"status.sync.revision",
+		"status.sync.revisions",
+		"status.reconciledAt",
+		"status.observedAt",
+	}
+	appFieldRef = regexp.MustCompile(`\bapp\b((?:\.[A-Za-z_][A-Za-z0-9_]*)*)`)
+)
+
+// fieldPruner removes application fields which are not used by triggers from objects cached by informers. Templates
+// are rendered using the full application fetched right before the rendering.
+type fieldPruner struct {
+	fields []string
+
+	lock sync.RWMutex
+	// triggerFields holds fields referenced by trigger conditions, nil if triggers use the whole application
+	triggerFields []string
+}
+
+func newFieldPruner(fields []string) *fieldPruner {
+	return &fieldPruner{fields: append(append([]string{}, requiredAppFields...), fields...)}
+}
+
+// setTriggers updates fields referenced by conditions of the triggers. Objects that have been already cached are
+// not updated until the next change of the object.
+func (p *fieldPruner) setTriggers(triggersByName map[string][]triggers.Condition) {
+	fields := map[string]bool{}
+	wholeObject := false
+	for name, conditions := range triggersByName {
+		for _, condition := range conditions {
+			for _, expr := range []string{condition.When, condition.OncePer} {
+				for _, match := range appFieldRef.FindAllStringSubmatch(expr, -1) {
+					if match[1] == "" {
+						log.Infof("Trigger %s uses the whole application, cached applications are not pruned", name)
+						wholeObject = true
+					} else {
+						fields[strings.TrimPrefix(match[1], ".")] = true
+					}
+				}
+			}
+		}
+	}
+	var res []string
+	if !wholeObject {
+		res = []string{}
+		for field := range fields {
+			res = append(res, field)
+		}
+		sort.Strings(res)
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.triggerFields = res
+}
+
+// prune returns the copy of the application which has only fields used by the controller and triggers
+func (p *fieldPruner) prune(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	p.lock.RLock()
+	triggerFields := p.triggerFields
+	p.lock.RUnlock()
+	if triggerFields == nil {
+		return obj
+	}
+	res := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	res.SetAPIVersion(obj.GetAPIVersion())
+	res.SetKind(obj.GetKind())
+	for _, fields := range [][]string{p.fields, triggerFields} {
+		for _, field := range fields {
+			path := strings.Split(field, ".")
+			if val, ok, err := unstructured.NestedFieldNoCopy(obj.Object, path...); ok && err == nil {
+				_ = unstructured.SetNestedField(res.Object, runtime.DeepCopyJSONValue(val), path...)
+			}
+		}
+	}
+	unstructured.RemoveNestedField(res.Object, "metadata", "managedFields")
+	if annotations := res.GetAnnotations(); annotations[lastAppliedConfigAnnotation] != "" {
+		delete(annotations, lastAppliedConfigAnnotation)
+		res.SetAnnotations(annotations)
+	}
+	return res
+}
+
+// pruningClient prunes applications listed and watched by informers
+type pruningClient struct {
+	dynamic.ResourceInterface
+	pruner *fieldPruner
+}
+
+func (c *pruningClient) List(ctx context.Context, opts v1.ListOptions) (*unstructured.UnstructuredList, error) {
+	list, err := c.ResourceInterface.List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list.Items {
+		list.Items[i] = *c.pruner.prune(&list.Items[i])
+	}
+	return list, nil
+}
+
+func (c *pruningClient) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	w, err := c.ResourceInterface.Watch(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return watch.Filter(w, func(in watch.Event) (watch.Event, bool) {
+		if obj, ok := in.Object.(*unstructured.Unstructured); ok {
+			in.Object = c.pruner.prune(obj)
+		}
+		return in, true
+	}), nil
+}
+
+// getFullApp returns the application with all fields if cached applications are pruned
+func (c *notificationController) getFullApp(obj map[string]interface{}) (map[string]interface{}, error) {
+	app := &unstructured.Unstructured{Object: obj}
+	full, err := c.client.Resource(k8s.Applications).Namespace(app.GetNamespace()).Get(context.Background(), app.GetName(), v1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if stale, ok := obj[staleSyncStatusFieldName]; ok {
+		full.Object[staleSyncStatusFieldName] = stale
+	}
+	return full.Object, nil
+}
@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/argoproj/notifications-engine/pkg/triggers"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/argoproj-labs/argocd-notifications/shared/k8s"
+	. "github.com/argoproj-labs/argocd-notifications/testing"
+)
+
+func TestFieldPruner(t *testing.T) {
+	app := NewApp("test", WithSyncStatus("Synced"), WithHealthStatus("Healthy"), WithProject("default"), WithAnnotations(map[string]string{
+		"notifications.argoproj.io/subscribe.on-synced.slack": "my-channel",
+		lastAppliedConfigAnnotation:                           "{}",
+	}))
+	_ = unstructured.SetNestedSlice(app.Object, []interface{}{map[string]interface{}{"kind": "Deployment"}}, "status", "resources")
+
+	pruner := newFieldPruner([]string{"status.summary"})
+	assert.Same(t, app, pruner.prune(app))
+
+	pruner.setTriggers(map[string][]triggers.Condition{
+		"on-synced": {{When: "app.status.sync.status == 'Synced'", OncePer: "app.status.sync.revision"}},
+	})
+	pruned := pruner.prune(app)
+	assert.Equal(t, "test", pruned.GetName())
+	assert.Equal(t, map[string]string{"notifications.argoproj.io/subscribe.on-synced.slack": "my-channel"}, pruned.GetAnnotations())
+	assert.Equal(t, app.GetObjectKind().GroupVersionKind(), pruned.GetObjectKind().GroupVersionKind())
+	status, _, _ := unstructured.NestedString(pruned.Object, "status", "sync", "status")
+	assert.Equal(t, "Synced", status)
+	project, _, _ := unstructured.NestedString(pruned.Object, "spec", "project")
+	assert.Equal(t, "default", project)
+	assert.NotContains(t, pruned.Object["status"], "health")
+	assert.NotContains(t, pruned.Object["status"], "resources")
+
+	pruner.setTriggers(map[string][]triggers.Condition{
+		"on-sync-running": {{When: "sync.GetInfoItem(app, 'reason') != ''"}},
+	})
+	assert.Same(t, app, pruner.prune(app))
+}
+
+func TestAppCachePruning(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	app := NewApp("test", WithHealthStatus("Healthy"))
+	ctrl, _, err := newController(t, ctx, NewFakeClient(app), WithAppCachePruning(true, nil))
+	assert.NoError(t, err)
+	ctrl.appPruner.setTriggers(map[string][]triggers.Condition{})
+
+	pruned := ctrl.appPruner.prune(app)
+	assert.NotContains(t, pruned.Object, "status")
+
+	full, err := ctrl.getFullApp(pruned.Object)
+	assert.NoError(t, err)
+	health, _, _ := unstructured.NestedString(full, "status", "health", "status")
+	assert.Equal(t, "Healthy", health)
+}
+
+func TestAppCachePruning_PrunesPatchedApp(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	app := NewApp("test", WithHealthStatus("Healthy"))
+	client := NewFakeClient(app)
+	ctrl, _, err := newController(t, ctx, client, WithAppCachePruning(true, nil))
+	assert.NoError(t, err)
+	ctrl.appPruner.setTriggers(map[string][]triggers.Condition{})
+
+	resClient := &resourceClient{NamespaceableResourceInterface: client.Resource(k8s.Applications), resource: k8s.Applications.Resource, informer: ctrl.appInformer, ctrl: ctrl}
+	patched, err := resClient.Namespace(TestNamespace).Patch(ctx, "test", types.MergePatchType, []byte(`{"metadata":{"annotations":{"foo":"bar"}}}`), metav1.PatchOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", patched.GetAnnotations()["foo"])
+	assert.NotContains(t, patched.Object, "status")
+}
@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/argoproj-labs/argocd-notifications/shared/k8s"
+	"github.com/argoproj-labs/argocd-notifications/shared/settings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// getActiveQuietWindow returns the quiet window which mutes the trigger of the resource at the given time and the
+// end of the window
+func (c *notificationController) getActiveQuietWindow(windows settings.QuietWindows, resource string, obj *unstructured.Unstructured, trigger string, now time.Time) (*settings.QuietWindow, time.Time) {
+	for i := range windows {
+		w := windows[i]
+		if !w.Mutes(trigger) {
+			continue
+		}
+		var end time.Time
+		var active bool
+		if w.ProjectSyncWindows {
+			if resource != k8s.Applications.Resource {
+				continue
+			}
+			if proj := getAppProj(obj, c.appProjInformer, c.namespace); proj != nil {
+				end, active = getDenySyncWindowEnd(proj, obj, now)
+			}
+		} else {
+			end, active = w.ActiveUntil(now)
+		}
+		if active {
+			return &w, end
+		}
+	}
+	return nil, time.Time{}
+}
+
+// getDenySyncWindowEnd returns the latest end of the active deny sync windows of the project that match the application
+func getDenySyncWindowEnd(proj *unstructured.Unstructured, app *unstructured.Unstructured, now time.Time) (time.Time, bool) {
+	windows, _, _ := unstructured.NestedSlice(proj.Object, "spec", "syncWindows")
+	destNamespace, _, _ := unstructured.NestedString(app.Object, "spec", "destination", "namespace")
+	destServer, _, _ := unstructured.NestedString(app.Object, "spec", "destination", "server")
+	var end time.Time
+	for _, item := range windows {
+		window, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if kind, _, _ := unstructured.NestedString(window, "kind"); kind != "deny" {
+			continue
+		}
+		applications, _, _ := unstructured.NestedStringSlice(window, "applications")
+		namespaces, _, _ := unstructured.NestedStringSlice(window, "namespaces")
+		clusters, _, _ := unstructured.NestedStringSlice(window, "clusters")
+		if !matchesAny(applications, app.GetName()) && !matchesAny(namespaces, destNamespace) && !matchesAny(clusters, destServer) {
+			continue
+		}
+		schedule, _, _ := unstructured.NestedString(window, "schedule")
+		duration, _, _ := unstructured.NestedString(window, "duration")
+		timezone, _, _ := unstructured.NestedString(window, "timeZone")
+		if windowEnd, active := settings.SyncWindowActiveUntil(schedule, duration, timezone, now); active && windowEnd.After(end) {
+			end = windowEnd
+		}
+	}
+	return end, !end.IsZero()
+}
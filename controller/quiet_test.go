@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/argoproj/notifications-engine/pkg/services"
+	"github.com/argoproj/notifications-engine/pkg/triggers"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/argoproj-labs/argocd-notifications/shared/k8s"
+	"github.com/argoproj-labs/argocd-notifications/shared/settings"
+	. "github.com/argoproj-labs/argocd-notifications/testing"
+)
+
+func TestGetDenySyncWindowEnd(t *testing.T) {
+	now := time.Date(2021, 11, 17, 10, 30, 0, 0, time.UTC)
+	proj := NewProject("default", WithSyncWindows(
+		map[string]interface{}{"kind": "allow", "schedule": "0 10 * * *", "duration": "4h", "applications": []interface{}{"*"}},
+		map[string]interface{}{"kind": "deny", "schedule": "0 10 * * *", "duration": "1h", "namespaces": []interface{}{"prod-*"}},
+		map[string]interface{}{"kind": "deny", "schedule": "0 9 * * *", "duration": "3h", "applications": []interface{}{"billing"}},
+	))
+
+	end, active := getDenySyncWindowEnd(proj, NewApp("guestbook", WithDestinationNamespace("prod-eu")), now)
+	assert.True(t, active)
+	assert.Equal(t, time.Date(2021, 11, 17, 11, 0, 0, 0, time.UTC), end)
+
+	end, active = getDenySyncWindowEnd(proj, NewApp("billing", WithDestinationNamespace("prod-eu")), now)
+	assert.True(t, active)
+	assert.Equal(t, time.Date(2021, 11, 17, 12, 0, 0, 0, time.UTC), end)
+
+	_, active = getDenySyncWindowEnd(proj, NewApp("guestbook", WithDestinationNamespace("staging")), now)
+	assert.False(t, active)
+}
+
+func TestGetActiveQuietWindow(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	app := NewApp("guestbook", WithProject("default"), WithDestinationNamespace("prod-eu"))
+	proj := NewProject("default", WithSyncWindows(
+		map[string]interface{}{"kind": "deny", "schedule": "0 10 * * *", "duration": "1h", "namespaces": []interface{}{"prod-*"}},
+	))
+	ctrl, _, err := newController(t, ctx, NewFakeClient(app, proj))
+	assert.NoError(t, err)
+
+	windows, err := settings.ParseQuietWindows(&v1.ConfigMap{Data: map[string]string{
+		"quietWindow.maintenance": `
+projectSyncWindows: true
+triggers: [on-deployed]
+`,
+	}})
+	assert.NoError(t, err)
+
+	window, end := ctrl.getActiveQuietWindow(windows, k8s.Applications.Resource, app, "on-deployed", time.Date(2021, 11, 17, 10, 30, 0, 0, time.UTC))
+	if assert.NotNil(t, window) {
+		assert.Equal(t, "maintenance", window.Name)
+		assert.Equal(t, time.Date(2021, 11, 17, 11, 0, 0, 0, time.UTC), end)
+	}
+	window, _ = ctrl.getActiveQuietWindow(windows, k8s.Applications.Resource, app, "on-sync-failed", time.Date(2021, 11, 17, 10, 30, 0, 0, time.UTC))
+	assert.Nil(t, window)
+	window, _ = ctrl.getActiveQuietWindow(windows, k8s.Applications.Resource, app, "on-deployed", time.Date(2021, 11, 17, 12, 0, 0, 0, time.UTC))
+	assert.Nil(t, window)
+}
+
+func TestSend_SummarizesMutedNotifications(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	app := NewApp("guestbook")
+	ctrl, mockAPI, err := newController(t, ctx, NewFakeClient(app))
+	assert.NoError(t, err)
+	svc := &fakeService{}
+	mockAPI.EXPECT().GetNotificationServices().Return(map[string]services.NotificationService{"mock": svc}).AnyTimes()
+	mockAPI.EXPECT().RunTrigger("on-deployed", gomock.Any()).Return([]triggers.ConditionResult{{Triggered: true}}, nil)
+
+	notificationsAPI := newTestAPI(t, mockAPI, ctrl)
+	notificationsAPI.quietWindows, err = settings.ParseQuietWindows(&v1.ConfigMap{Data: map[string]string{
+		"quietWindow.always": `
+schedule: "* * * * *"
+duration: 1m
+action: summary
+`,
+	}})
+	assert.NoError(t, err)
+
+	_, err = notificationsAPI.RunTrigger("on-deployed", app.Object)
+	assert.NoError(t, err)
+	assert.NoError(t, notificationsAPI.Send(app.Object, []string{"my-template"}, services.Destination{Service: "mock", Recipient: "recipient"}))
+	assert.Empty(t, svc.sent)
+
+	ctrl.digester.flush(time.Now().Add(time.Minute))
+	assert.Equal(t, []services.Notification{{
+		Message: "1 notification(s) were muted by quiet window always:\n- default/guestbook: on-deployed",
+	}}, svc.sent)
+}
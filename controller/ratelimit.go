@@ -0,0 +1,181 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/argoproj-labs/argocd-notifications/shared/settings"
+
+	"github.com/argoproj/notifications-engine/pkg/controller"
+	"github.com/argoproj/notifications-engine/pkg/services"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+const (
+	rateLimitReportInterval = time.Second
+
+	rateLimitedDropped = "dropped"
+	rateLimitedQueued  = "queued"
+)
+
+// rateLimiter throttles notifications using token buckets of services, recipients and applications
+type rateLimiter struct {
+	lock       sync.Mutex
+	buckets    map[string]*bucket
+	suppressed map[string]*suppression
+	counter    *prometheus.CounterVec
+}
+
+type bucket struct {
+	limit    settings.Limit
+	limiter  *rate.Limiter
+	lastUsed time.Time
+}
+
+// idle returns true if the bucket has been refilled since the last use
+func (b *bucket) idle(now time.Time) bool {
+	return now.Sub(b.lastUsed) > time.Duration(b.limit.GetBurst())*b.limit.Interval.Duration/time.Duration(b.limit.Limit)
+}
+
+// suppression counts notifications dropped until the end of the rate limit window
+type suppression struct {
+	service services.NotificationService
+	dest    services.Destination
+	count   int
+	until   time.Time
+}
+
+func newRateLimiter(registry *controller.MetricsRegistry) *rateLimiter {
+	counter := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "argocd_notifications_rate_limited_total",
+			Help: "Number of notifications over the rate limit.",
+		},
+		[]string{"service", "action"},
+	)
+	if registry != nil {
+		registry.MustRegister(counter)
+	}
+	return &rateLimiter{buckets: map[string]*bucket{}, suppressed: map[string]*suppression{}, counter: counter}
+}
+
+func (l *rateLimiter) getBucket(key string, limit settings.Limit, now time.Time) *bucket {
+	b, ok := l.buckets[key]
+	if !ok || b.limit != limit {
+		b = &bucket{limit: limit, limiter: rate.NewLimiter(rate.Limit(float64(limit.Limit)/limit.Interval.Seconds()), limit.GetBurst())}
+		l.buckets[key] = b
+	}
+	b.lastUsed = now
+	return b
+}
+
+// reserve takes tokens required to send the notification about the resource with the given key. It returns false if
+// the notification must be dropped or the delay after which the notification can be sent.
+func (l *rateLimiter) reserve(policy *settings.RateLimitPolicy, key string, dest services.Destination) (time.Duration, bool) {
+	if policy == nil {
+		return 0, true
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	limits := map[string]*settings.Limit{
+		"service/" + dest.Service:                          policy.Service,
+		"recipient/" + dest.Service + "/" + dest.Recipient: policy.Recipient,
+		"application/" + dest.Service + "/" + key:          policy.Application,
+	}
+	var reservations []*rate.Reservation
+	cancel := func() {
+		for _, r := range reservations {
+			r.CancelAt(now)
+		}
+	}
+	var delay time.Duration
+	for bucketKey, limit := range limits {
+		if limit == nil {
+			continue
+		}
+		r := l.getBucket(bucketKey, *limit, now).limiter.ReserveN(now, 1)
+		if !r.OK() {
+			cancel()
+			return 0, false
+		}
+		reservations = append(reservations, r)
+		if d := r.DelayFrom(now); d > delay {
+			delay = d
+		}
+	}
+	if delay == 0 {
+		return 0, true
+	}
+	if policy.Action == settings.RateLimitActionQueue && delay <= policy.MaxQueueDelay.Duration {
+		l.counter.WithLabelValues(dest.Service, rateLimitedQueued).Inc()
+		return delay, true
+	}
+	cancel()
+	l.counter.WithLabelValues(dest.Service, rateLimitedDropped).Inc()
+	return delay, false
+}
+
+// suppress counts the dropped notification so that the recipient is notified about it once the limit allows
+func (l *rateLimiter) suppress(service services.NotificationService, dest services.Destination, delay time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	key := formatDestination(dest)
+	s, ok := l.suppressed[key]
+	if !ok {
+		s = &suppression{service: service, dest: dest}
+		l.suppressed[key] = s
+	}
+	s.count++
+	if until := time.Now().Add(delay); until.After(s.until) {
+		s.until = until
+	}
+}
+
+// run sends reports about suppressed notifications and removes idle buckets until the context is done
+func (l *rateLimiter) run(ctx context.Context) {
+	ticker := time.NewTicker(rateLimitReportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			l.cleanup(now)
+			for _, s := range l.expiredSuppressions(now) {
+				notification := services.Notification{Message: fmt.Sprintf("%d notification(s) were suppressed due to the rate limit", s.count)}
+				if err := s.service.Send(notification, s.dest); err != nil {
+					log.Errorf("Failed to report suppressed notifications to %s: %v", formatDestination(s.dest), err)
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (l *rateLimiter) expiredSuppressions(now time.Time) []*suppression {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	var res []*suppression
+	for key, s := range l.suppressed {
+		if now.After(s.until) {
+			res = append(res, s)
+			delete(l.suppressed, key)
+		}
+	}
+	return res
+}
+
+func (l *rateLimiter) cleanup(now time.Time) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	for key, b := range l.buckets {
+		if b.idle(now) {
+			delete(l.buckets, key)
+		}
+	}
+}
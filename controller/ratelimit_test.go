@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/argoproj/notifications-engine/pkg/services"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/argoproj-labs/argocd-notifications/shared/settings"
+)
+
+var slackGeneral = services.Destination{Service: "slack", Recipient: "general"}
+
+func TestRateLimiter_NoPolicy(t *testing.T) {
+	limiter := newRateLimiter(nil)
+	for i := 0; i < 10; i++ {
+		delay, allowed := limiter.reserve(nil, "applications/default/guestbook", slackGeneral)
+		assert.True(t, allowed)
+		assert.Zero(t, delay)
+	}
+}
+
+func TestRateLimiter_Drop(t *testing.T) {
+	limiter := newRateLimiter(nil)
+	policy := &settings.RateLimitPolicy{
+		Recipient: &settings.Limit{Limit: 1, Interval: metav1.Duration{Duration: time.Hour}},
+		Action:    settings.RateLimitActionDrop,
+	}
+
+	_, allowed := limiter.reserve(policy, "applications/default/guestbook", slackGeneral)
+	assert.True(t, allowed)
+	delay, allowed := limiter.reserve(policy, "applications/default/guestbook", slackGeneral)
+	assert.False(t, allowed)
+	assert.InDelta(t, time.Hour, delay, float64(time.Second))
+	_, allowed = limiter.reserve(policy, "applications/default/guestbook", services.Destination{Service: "slack", Recipient: "other"})
+	assert.True(t, allowed)
+}
+
+func TestRateLimiter_DropDoesNotConsumeOtherBuckets(t *testing.T) {
+	limiter := newRateLimiter(nil)
+	policy := &settings.RateLimitPolicy{
+		Service:     &settings.Limit{Limit: 2, Interval: metav1.Duration{Duration: time.Hour}},
+		Application: &settings.Limit{Limit: 1, Interval: metav1.Duration{Duration: time.Hour}},
+		Action:      settings.RateLimitActionDrop,
+	}
+
+	_, allowed := limiter.reserve(policy, "applications/default/foo", slackGeneral)
+	assert.True(t, allowed)
+	_, allowed = limiter.reserve(policy, "applications/default/foo", slackGeneral)
+	assert.False(t, allowed)
+	_, allowed = limiter.reserve(policy, "applications/default/bar", slackGeneral)
+	assert.True(t, allowed)
+}
+
+func TestRateLimiter_Queue(t *testing.T) {
+	limiter := newRateLimiter(nil)
+	policy := &settings.RateLimitPolicy{
+		Recipient:     &settings.Limit{Limit: 1, Interval: metav1.Duration{Duration: time.Minute}},
+		Action:        settings.RateLimitActionQueue,
+		MaxQueueDelay: metav1.Duration{Duration: 90 * time.Second},
+	}
+
+	_, allowed := limiter.reserve(policy, "applications/default/guestbook", slackGeneral)
+	assert.True(t, allowed)
+	delay, allowed := limiter.reserve(policy, "applications/default/guestbook", slackGeneral)
+	assert.True(t, allowed)
+	assert.InDelta(t, time.Minute, delay, float64(time.Second))
+	_, allowed = limiter.reserve(policy, "applications/default/guestbook", slackGeneral)
+	assert.False(t, allowed)
+}
+
+func TestRateLimiter_ReportsSuppressed(t *testing.T) {
+	limiter := newRateLimiter(nil)
+	svc := &fakeService{}
+	limiter.suppress(svc, slackGeneral, time.Minute)
+	limiter.suppress(svc, slackGeneral, time.Second)
+
+	assert.Empty(t, limiter.expiredSuppressions(time.Now()))
+	expired := limiter.expiredSuppressions(time.Now().Add(2 * time.Minute))
+	if assert.Len(t, expired, 1) {
+		assert.Equal(t, 2, expired[0].count)
+	}
+	assert.Empty(t, limiter.suppressed)
+}
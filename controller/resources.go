@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/argoproj-labs/argocd-notifications/shared/k8s"
+	"github.com/argoproj-labs/argocd-notifications/shared/settings"
+
+	"github.com/argoproj/notifications-engine/pkg/api"
+	"github.com/argoproj/notifications-engine/pkg/controller"
+	"github.com/argoproj/notifications-engine/pkg/services"
+	"github.com/argoproj/notifications-engine/pkg/subscriptions"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// customResourceController sends notifications about a resource listed in the notifications ConfigMap
+type customResourceController struct {
+	resource settings.CustomResource
+	informer cache.SharedIndexInformer
+	ctrl     controller.NotificationController
+}
+
+// initCustomResources creates controllers of the resources listed in the notifications ConfigMap.
+// The list is loaded once, so changes require the controller restart.
+func (c *notificationController) initCustomResources() error {
+	obj, exists, err := c.configMapInformer.GetIndexer().GetByKey(fmt.Sprintf("%s/%s", c.namespace, k8s.ConfigMapName))
+	if err != nil || !exists {
+		return err
+	}
+	configMap, ok := obj.(*v1.ConfigMap)
+	if !ok {
+		return nil
+	}
+	resources, err := settings.ParseCustomResources(configMap)
+	if err != nil {
+		return err
+	}
+	var customResources []*customResourceController
+	for _, r := range resources {
+		gvr := r.GroupVersionResource()
+		resClient := c.client.Resource(gvr)
+		informer := newInformer(c.newResourceClient(gvr.GroupResource().String(), resClient), r.LabelSelector, c.getResourceFilter())
+		informer.AddEventHandler(c.tracer.eventHandler(gvr.GroupResource().String()))
+		customResources = append(customResources, &customResourceController{
+			resource: r,
+			informer: informer,
+			ctrl: controller.NewController(
+				&resourceClient{NamespaceableResourceInterface: resClient, resource: gvr.GroupResource().String(), informer: informer, ctrl: c},
+				informer,
+				c.newAPIFactory(gvr.GroupResource().String(), settings.GetCustomResourceFactorySettings(c.argocdService, r, c.resourceContext(gvr.GroupResource().String()))),
+				controller.WithSkipProcessing(c.skipProcessing(gvr.GroupResource().String(), nil)),
+				controller.WithMetricsRegistry(c.engineRegistry),
+				controller.WithAlterDestinations(c.alterResourceDestinations)),
+		})
+		log.Infof("Watching %s, available in templates as '%s'", gvr.String(), r.GetVarName())
+	}
+	// the debug server lists informers concurrently with the initialization
+	c.customResourcesLock.Lock()
+	c.customResources = customResources
+	c.customResourcesLock.Unlock()
+	return nil
+}
+
+// alterResourceDestinations ignores default triggers of resources other than applications since
+// default triggers are meant for applications: such resources must be subscribed to the specific triggers.
+func (c *notificationController) alterResourceDestinations(obj metav1.Object, _ services.Destinations, cfg api.Config) services.Destinations {
+	cfg.DefaultTriggers = nil
+	destinations := cfg.GetGlobalDestinations(obj.GetLabels())
+	destinations.Merge(subscriptions.NewAnnotations(obj.GetAnnotations()).GetDestinations(nil, nil))
+	return destinations
+}
@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/argoproj/notifications-engine/pkg/controller"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/argoproj-labs/argocd-notifications/shared/k8s"
+	. "github.com/argoproj-labs/argocd-notifications/testing"
+)
+
+func TestInitCustomResources(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+
+	rollouts := schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "rollouts"}
+	rollout := &unstructured.Unstructured{}
+	rollout.SetGroupVersionKind(schema.GroupVersionKind{Group: "argoproj.io", Version: "v1alpha1", Kind: "Rollout"})
+	rollout.SetName("guestbook")
+	rollout.SetNamespace(TestNamespace)
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		k8s.Applications: "List",
+		k8s.AppProjects:  "List",
+		rollouts:         "List",
+	}, rollout)
+	clientset := fake.NewSimpleClientset(&v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: k8s.ConfigMapName, Namespace: TestNamespace},
+		Data: map[string]string{"resources": `
+- group: argoproj.io
+  version: v1alpha1
+  resource: rollouts
+  varName: rollout
+`},
+	})
+
+	ctrl := NewController(clientset, client, nil, TestNamespace, "", controller.NewMetricsRegistry("argocd"))
+	// the debug server lists informers while the controller is being initialized
+	listed := make(chan struct{})
+	go func() {
+		defer close(listed)
+		for ctx.Err() == nil && len(ctrl.Informers()) < 5 {
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	err := ctrl.Init(ctx)
+	assert.NoError(t, err)
+	<-listed
+
+	if assert.Len(t, ctrl.customResources, 1) {
+		assert.Equal(t, "rollout", ctrl.customResources[0].resource.GetVarName())
+		assert.Len(t, ctrl.customResources[0].informer.GetStore().List(), 1)
+	}
+	assert.Contains(t, ctrl.Informers(), "rollouts.argoproj.io")
+}
@@ -0,0 +1,176 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/argoproj-labs/argocd-notifications/shared/configmap"
+)
+
+const (
+	DefaultConfigMapName = "argocd-notifications-state"
+	// StoreLabel is the label of ConfigMaps which store the state, the label value is the name of the store
+	StoreLabel = "notifications.argoproj.io/state-store"
+
+	// configMapCount is the number of ConfigMaps the state of a namespace is split into
+	configMapCount = 8
+)
+
+// Key identifies the resource which notifications state is stored
+type Key struct {
+	// Resource is the group resource of the resource, e.g. applications
+	Resource  string
+	Namespace string
+	Name      string
+}
+
+// Store persists notifications state of resources outside of the resources so that the controller does not
+// modify resources. The state is the value of the annotation used by notifications-engine to store the state.
+type Store interface {
+	// Get returns the state of the resource and false if the store has no state of the resource
+	Get(ctx context.Context, key Key) (string, bool, error)
+	// Set saves the state of the resource. The empty state removes the resource from the store.
+	Set(ctx context.Context, key Key, state string) error
+}
+
+// configMapStore keeps the state of resources of a namespace in a fixed number of ConfigMaps of that namespace. The
+// resource state is stored in the ConfigMap picked by the hash of the resource, so that the state of many resources
+// does not have to fit into a single ConfigMap. ConfigMaps are read from the informer of the namespace, which is
+// started once the state of a resource from that namespace is requested.
+type configMapStore struct {
+	ctx       context.Context
+	clientset kubernetes.Interface
+	name      string
+
+	lock      sync.Mutex
+	informers map[string]cache.SharedIndexInformer
+}
+
+// NewConfigMapStore returns the store which keeps the state in the ConfigMaps with the given name prefix in the
+// namespace of each resource. Informers of the ConfigMaps are stopped once the given context is done.
+func NewConfigMapStore(ctx context.Context, clientset kubernetes.Interface, name string) Store {
+	return &configMapStore{ctx: ctx, clientset: clientset, name: name, informers: map[string]cache.SharedIndexInformer{}}
+}
+
+func dataKey(key Key) string {
+	return key.Resource + "." + key.Name
+}
+
+// configMapName returns the name of the ConfigMap which stores the state of the resource
+func (s *configMapStore) configMapName(key Key) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(dataKey(key)))
+	return fmt.Sprintf("%s-%d", s.name, h.Sum32()%configMapCount)
+}
+
+// getInformer returns the synced informer of state ConfigMaps in the namespace
+func (s *configMapStore) getInformer(ctx context.Context, namespace string) (cache.SharedIndexInformer, error) {
+	s.lock.Lock()
+	informer, ok := s.informers[namespace]
+	if !ok {
+		informer = corev1.NewFilteredConfigMapInformer(s.clientset, namespace, 0, cache.Indexers{}, func(options *metav1.ListOptions) {
+			options.LabelSelector = fmt.Sprintf("%s=%s", StoreLabel, s.name)
+		})
+		go informer.Run(s.ctx.Done())
+		s.informers[namespace] = informer
+	}
+	s.lock.Unlock()
+	if !informer.HasSynced() && !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		return nil, fmt.Errorf("timed out waiting for state ConfigMaps of namespace %s to sync", namespace)
+	}
+	return informer, nil
+}
+
+func (s *configMapStore) Get(ctx context.Context, key Key) (string, bool, error) {
+	informer, err := s.getInformer(ctx, key.Namespace)
+	if err != nil {
+		return "", false, err
+	}
+	obj, ok, err := informer.GetIndexer().GetByKey(key.Namespace + "/" + s.configMapName(key))
+	if err != nil || !ok {
+		return "", false, err
+	}
+	cm, ok := obj.(*v1.ConfigMap)
+	if !ok {
+		return "", false, nil
+	}
+	val, ok := cm.Data[dataKey(key)]
+	return val, ok, nil
+}
+
+func (s *configMapStore) Set(ctx context.Context, key Key, state string) error {
+	if current, ok, err := s.Get(ctx, key); err != nil {
+		return err
+	} else if ok == (state != "") && current == state {
+		return nil
+	}
+	informer, err := s.getInformer(ctx, key.Namespace)
+	if err != nil {
+		return err
+	}
+
+	name := s.configMapName(key)
+	updated, err := configmap.Update(ctx, s.clientset, key.Namespace, name, func(cm *v1.ConfigMap) error {
+		if cm.Labels == nil {
+			cm.Labels = map[string]string{}
+		}
+		cm.Labels[StoreLabel] = s.name
+		if state == "" {
+			delete(cm.Data, dataKey(key))
+			return nil
+		}
+		cm.Data[dataKey(key)] = state
+		if size := configmap.DataSize(cm.Data); size > configmap.MaxDataSize {
+			return fmt.Errorf("state ConfigMap %s/%s is full: %d bytes", key.Namespace, name, size)
+		}
+		return nil
+	})
+	if err != nil || updated == nil {
+		return err
+	}
+	// the informer is updated right away so that the state is not read before the informer receives the update
+	return informer.GetIndexer().Update(updated)
+}
+
+// memoryStore keeps the state in memory and reads the state missing in memory from the underlying store
+type memoryStore struct {
+	base Store
+
+	lock sync.Mutex
+	// data holds the state by resource, the empty state means that the state has been removed
+	data map[Key]string
+}
+
+// NewMemoryStore returns the store which never persists the state: the state is kept in memory on top of the state
+// of the given store, which is never modified. The base store might be nil.
+func NewMemoryStore(base Store) Store {
+	return &memoryStore{base: base, data: map[Key]string{}}
+}
+
+func (s *memoryStore) Get(ctx context.Context, key Key) (string, bool, error) {
+	s.lock.Lock()
+	val, ok := s.data[key]
+	s.lock.Unlock()
+	if ok {
+		return val, val != "", nil
+	}
+	if s.base == nil {
+		return "", false, nil
+	}
+	return s.base.Get(ctx, key)
+}
+
+func (s *memoryStore) Set(_ context.Context, key Key, state string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.data[key] = state
+	return nil
+}
@@ -0,0 +1,102 @@
+package state
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/argoproj-labs/argocd-notifications/shared/configmap"
+)
+
+func TestConfigMapStore(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	clientset := fake.NewSimpleClientset()
+	store := NewConfigMapStore(ctx, clientset, DefaultConfigMapName)
+	key := Key{Resource: "applications", Namespace: "team-a", Name: "guestbook"}
+	name := store.(*configMapStore).configMapName(key)
+	assert.True(t, strings.HasPrefix(name, DefaultConfigMapName+"-"))
+
+	_, ok, err := store.Get(ctx, key)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, store.Set(ctx, key, `{"on-deployed:abc:slack:general":1637168171}`))
+	cm, err := clientset.CoreV1().ConfigMaps("team-a").Get(ctx, name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"applications.guestbook": `{"on-deployed:abc:slack:general":1637168171}`}, cm.Data)
+	assert.Equal(t, DefaultConfigMapName, cm.Labels[StoreLabel])
+
+	restored := NewConfigMapStore(ctx, clientset, DefaultConfigMapName)
+	val, ok, err := restored.Get(ctx, key)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, `{"on-deployed:abc:slack:general":1637168171}`, val)
+
+	assert.NoError(t, restored.Set(ctx, key, ""))
+	_, ok, err = restored.Get(ctx, key)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	cm, err = clientset.CoreV1().ConfigMaps("team-a").Get(ctx, name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, cm.Data)
+}
+
+func TestConfigMapStore_ReadsUpdatedState(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	clientset := fake.NewSimpleClientset()
+	store := NewConfigMapStore(ctx, clientset, DefaultConfigMapName)
+	other := NewConfigMapStore(ctx, clientset, DefaultConfigMapName)
+	key := Key{Resource: "applications", Namespace: "team-a", Name: "guestbook"}
+	assert.NoError(t, store.Set(ctx, key, `{"on-deployed":1}`))
+
+	assert.NoError(t, other.Set(ctx, key, `{"on-deployed":2}`))
+	assert.Eventually(t, func() bool {
+		val, _, err := store.Get(ctx, key)
+		return err == nil && val == `{"on-deployed":2}`
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestConfigMapStore_SizeLimit(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	store := NewConfigMapStore(ctx, fake.NewSimpleClientset(), DefaultConfigMapName)
+	key := Key{Resource: "applications", Namespace: "team-a", Name: "guestbook"}
+	assert.NoError(t, store.Set(ctx, key, `{"on-deployed":1}`))
+
+	assert.Error(t, store.Set(ctx, key, strings.Repeat("a", configmap.MaxDataSize)))
+	val, _, err := store.Get(ctx, key)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"on-deployed":1}`, val)
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	clientset := fake.NewSimpleClientset()
+	base := NewConfigMapStore(ctx, clientset, DefaultConfigMapName)
+	key := Key{Resource: "applications", Namespace: "team-a", Name: "guestbook"}
+	assert.NoError(t, base.Set(ctx, key, `{"on-deployed:abc:slack:general":1637168171}`))
+
+	store := NewMemoryStore(base)
+	val, ok, err := store.Get(ctx, key)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, `{"on-deployed:abc:slack:general":1637168171}`, val)
+
+	assert.NoError(t, store.Set(ctx, key, ""))
+	_, ok, err = store.Get(ctx, key)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	val, ok, err = base.Get(ctx, key)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, `{"on-deployed:abc:slack:general":1637168171}`, val)
+}
@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"sync"
+	"time"
+
+	"github.com/argoproj/notifications-engine/pkg/controller"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// staleSyncStatusFieldName is the field of the application available in triggers and templates which is true if the
+	// application is processed although its sync status has not been refreshed after the last operation
+	staleSyncStatusFieldName = "staleSyncStatus"
+)
+
+// syncStatusWaiter limits how long processing of applications is postponed until Argo CD refreshes the sync status
+// after the completed operation
+type syncStatusWaiter struct {
+	maxWait time.Duration
+	counter prometheus.Counter
+	// stale holds keys of applications which are being processed with the stale sync status
+	stale sync.Map
+}
+
+func newSyncStatusWaiter(registry *controller.MetricsRegistry) *syncStatusWaiter {
+	counter := prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "argocd_notifications_stale_sync_status_total",
+		Help: "Number of times applications were processed without waiting any longer for the sync status refresh.",
+	})
+	if registry != nil {
+		registry.MustRegister(counter)
+	}
+	return &syncStatusWaiter{counter: counter}
+}
+
+// skip returns true if processing of the application should be postponed until its sync status is refreshed. Once the
+// maximum wait is exceeded the application is processed and marked as having the stale sync status.
+func (w *syncStatusWaiter) skip(app *unstructured.Unstructured, logEntry *log.Entry, now time.Time) bool {
+	key := app.GetNamespace() + "/" + app.GetName()
+	if isAppSyncStatusRefreshed(app, logEntry) {
+		w.stale.Delete(key)
+		return false
+	}
+	finishedAt, ok := getOperationFinishedAt(app)
+	if w.maxWait <= 0 || !ok || now.Sub(finishedAt) < w.maxWait {
+		w.stale.Delete(key)
+		return true
+	}
+	// the application is counted once per operation rather than on every resync
+	if _, loaded := w.stale.LoadOrStore(key, true); !loaded {
+		logEntry.Warnf("SyncStatus has not been refreshed within %v after the operation finished at %v, processing anyway", w.maxWait, finishedAt)
+		w.counter.Inc()
+	}
+	return false
+}
+
+// toUnstructured returns the application processed by the notifications-engine controller: the copy of the
+// application with the stale sync status flag is returned if the application is processed with the stale sync status
+func (w *syncStatusWaiter) toUnstructured(app *unstructured.Unstructured) *unstructured.Unstructured {
+	if _, ok := w.stale.Load(app.GetNamespace() + "/" + app.GetName()); !ok {
+		return app
+	}
+	res := app.DeepCopy()
+	res.Object[staleSyncStatusFieldName] = true
+	return res
+}
+
+func getOperationFinishedAt(app *unstructured.Unstructured) (time.Time, bool) {
+	finishedAtRaw, ok, err := unstructured.NestedString(app.Object, "status", "operationState", "finishedAt")
+	if !ok || err != nil {
+		return time.Time{}, false
+	}
+	finishedAt, err := time.Parse(time.RFC3339, finishedAtRaw)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return finishedAt, true
+}
@@ -0,0 +1,40 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/argoproj-labs/argocd-notifications/testing"
+)
+
+func TestSyncStatusWaiter(t *testing.T) {
+	finishedAt := time.Date(2021, 11, 17, 10, 0, 0, 0, time.UTC)
+	app := NewApp("test",
+		WithSyncOperationPhase("Succeeded"),
+		WithSyncOperationFinishedAt(finishedAt),
+		WithReconciledAt(finishedAt.Add(-time.Minute)))
+
+	waiter := newSyncStatusWaiter(nil)
+	assert.True(t, waiter.skip(app, logEntry, finishedAt.Add(time.Hour)))
+
+	waiter.maxWait = 5 * time.Minute
+	assert.True(t, waiter.skip(app, logEntry, finishedAt.Add(time.Minute)))
+	assert.Same(t, app, waiter.toUnstructured(app))
+
+	assert.False(t, waiter.skip(app, logEntry, finishedAt.Add(10*time.Minute)))
+	assert.False(t, waiter.skip(app, logEntry, finishedAt.Add(20*time.Minute)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(waiter.counter))
+	stale := waiter.toUnstructured(app)
+	assert.Equal(t, true, stale.Object[staleSyncStatusFieldName])
+	assert.NotContains(t, app.Object, staleSyncStatusFieldName)
+
+	refreshed := NewApp("test",
+		WithSyncOperationPhase("Succeeded"),
+		WithSyncOperationFinishedAt(finishedAt),
+		WithReconciledAt(finishedAt.Add(time.Minute)))
+	assert.False(t, waiter.skip(refreshed, logEntry, finishedAt.Add(10*time.Minute)))
+	assert.Same(t, refreshed, waiter.toUnstructured(refreshed))
+}
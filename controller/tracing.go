@@ -0,0 +1,220 @@
+package controller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/argoproj-labs/argocd-notifications/shared/k8s"
+	"github.com/argoproj-labs/argocd-notifications/shared/settings"
+	"github.com/argoproj-labs/argocd-notifications/shared/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// traceIdleTimeout is how long the resource processing span is kept open after the last processing step
+	traceIdleTimeout = time.Second
+	traceSweepPeriod = time.Second
+
+	informerEventAdd    = "add"
+	informerEventUpdate = "update"
+
+	tracerName = "github.com/argoproj-labs/argocd-notifications/controller"
+)
+
+// processingTracer traces the notification pipeline of resources. The span of the resource processing starts when
+// the informer notifies about the resource change and ends once no processing step of the resource has been running
+// for traceIdleTimeout: the notifications-engine controller does not report the end of the resource processing.
+type processingTracer struct {
+	tracer trace.Tracer
+
+	lock sync.Mutex
+	// pending holds the first informer event of every resource which has not been processed yet, which also makes
+	// the number of resources waiting in the notifications-engine controller queue
+	pending map[string]informerEvent
+	// processing holds spans of resources being processed
+	processing map[string]*processing
+}
+
+type informerEvent struct {
+	resource  string
+	eventType string
+	time      time.Time
+}
+
+type processing struct {
+	span trace.Span
+	// ctx is the context of the currently running step, which is used by expression helpers
+	ctx        context.Context
+	active     int
+	lastActive time.Time
+}
+
+// tracedStep is the span of a resource processing step
+type tracedStep struct {
+	trace.Span
+	tracer *processingTracer
+	// processing is nil if the step is not a part of the resource processing
+	processing *processing
+	ctx        context.Context
+	prev       context.Context
+}
+
+func newProcessingTracer(tracer trace.Tracer) *processingTracer {
+	return &processingTracer{tracer: tracer, pending: map[string]informerEvent{}, processing: map[string]*processing{}}
+}
+
+// eventHandler records informer events of the resource so that the processing span starts at the event time
+func (t *processingTracer) eventHandler(resource string) cache.ResourceEventHandler {
+	record := func(eventType string, obj interface{}) {
+		key, err := cache.MetaNamespaceKeyFunc(obj)
+		if err != nil {
+			return
+		}
+		t.lock.Lock()
+		defer t.lock.Unlock()
+		if _, ok := t.pending[resource+"/"+key]; !ok {
+			t.pending[resource+"/"+key] = informerEvent{resource: resource, eventType: eventType, time: time.Now()}
+		}
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			record(informerEventAdd, obj)
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			record(informerEventUpdate, obj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj); err == nil {
+				t.lock.Lock()
+				delete(t.pending, resource+"/"+key)
+				t.lock.Unlock()
+			}
+		},
+	}
+}
+
+// start starts the processing span of the resource and ends the previous processing span of the same resource
+func (t *processingTracer) start(resource string, key string, obj v1.Object) {
+	now := time.Now()
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if p, ok := t.processing[key]; ok {
+		p.span.End(trace.WithTimestamp(p.lastActive))
+	}
+	attrs := []attribute.KeyValue{
+		attribute.String("resource", resource),
+		attribute.String("name", obj.GetNamespace()+"/"+obj.GetName()),
+	}
+	if resource == k8s.Applications.Resource {
+		attrs = append(attrs, attribute.String("app", obj.GetNamespace()+"/"+obj.GetName()))
+	}
+	startedAt := now
+	if event, ok := t.pending[key]; ok {
+		startedAt = event.time
+		attrs = append(attrs, attribute.String("informer.event", event.eventType))
+		delete(t.pending, key)
+	}
+	ctx, span := t.tracer.Start(context.Background(), "ProcessResource", trace.WithTimestamp(startedAt), trace.WithAttributes(attrs...))
+	t.processing[key] = &processing{span: span, ctx: ctx, lastActive: now}
+}
+
+// finish ends the processing span of the resource right away
+func (t *processingTracer) finish(key string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if p, ok := t.processing[key]; ok {
+		p.span.End()
+		delete(t.processing, key)
+	}
+}
+
+// context returns the context of the current processing step of the resource
+func (t *processingTracer) context(key string) context.Context {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if p, ok := t.processing[key]; ok {
+		return p.ctx
+	}
+	return context.Background()
+}
+
+// startStep starts the span of the resource processing step as a child of the current step
+func (t *processingTracer) startStep(key string, name string, attrs ...attribute.KeyValue) (context.Context, *tracedStep) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	parent := context.Background()
+	p := t.processing[key]
+	if p != nil {
+		parent = p.ctx
+	}
+	ctx, span := t.tracer.Start(parent, name, trace.WithAttributes(attrs...))
+	if p != nil {
+		p.ctx = ctx
+		p.active++
+	}
+	return ctx, &tracedStep{Span: span, tracer: t, processing: p, ctx: ctx, prev: parent}
+}
+
+// end records the error of the step, if any, and ends the step span
+func (s *tracedStep) end(err error) {
+	tracing.End(s.Span, err)
+	if s.processing == nil {
+		return
+	}
+	s.tracer.lock.Lock()
+	defer s.tracer.lock.Unlock()
+	if s.processing.ctx == s.ctx {
+		s.processing.ctx = s.prev
+	}
+	s.processing.active--
+	s.processing.lastActive = time.Now()
+}
+
+// pendingCounts returns the number of resources waiting to be processed by resource type
+func (t *processingTracer) pendingCounts() map[string]int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	res := map[string]int{}
+	for _, event := range t.pending {
+		res[event.resource]++
+	}
+	return res
+}
+
+// sweep ends processing spans of resources which processing steps have not been running for traceIdleTimeout
+func (t *processingTracer) sweep(now time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for key, p := range t.processing {
+		if p.active == 0 && now.Sub(p.lastActive) > traceIdleTimeout {
+			p.span.End(trace.WithTimestamp(p.lastActive))
+			delete(t.processing, key)
+		}
+	}
+}
+
+func (t *processingTracer) run(ctx context.Context) {
+	ticker := time.NewTicker(traceSweepPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			t.sweep(now)
+		}
+	}
+}
+
+// resourceContext returns the function which provides expression helpers with the context of the current processing
+// step of the resource, so that Argo CD calls are traced as a part of the resource processing
+func (c *notificationController) resourceContext(resource string) settings.ContextFunc {
+	return func(obj map[string]interface{}) context.Context {
+		return c.tracer.context(resource + "/" + resourceKey(obj))
+	}
+}
@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/argoproj/notifications-engine/pkg/services"
+	"github.com/argoproj/notifications-engine/pkg/triggers"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/argoproj-labs/argocd-notifications/shared/k8s"
+	. "github.com/argoproj-labs/argocd-notifications/testing"
+)
+
+func TestTracing_ProcessingSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	app := NewApp("test")
+	client := NewFakeClient(app)
+	ctrl, mockAPI, err := newController(t, ctx, client, WithTracerProvider(provider))
+	assert.NoError(t, err)
+	mockAPI.EXPECT().RunTrigger("my-trigger", gomock.Any()).Return([]triggers.ConditionResult{{
+		Key: "[0].abc", Triggered: true, Templates: []string{"my-template"},
+	}}, nil)
+	mockAPI.EXPECT().GetNotificationServices().Return(map[string]services.NotificationService{"mock": &fakeService{}}).AnyTimes()
+	notificationsAPI := newTestAPI(t, mockAPI, ctrl)
+
+	skipped, _ := ctrl.skipProcessing(k8s.Applications.Resource, nil)(app)
+	assert.False(t, skipped)
+	_, err = notificationsAPI.RunTrigger("my-trigger", app.Object)
+	assert.NoError(t, err)
+	assert.NoError(t, notificationsAPI.Send(app.Object, []string{"my-template"}, services.Destination{Service: "mock", Recipient: "recipient"}))
+	resClient := &resourceClient{NamespaceableResourceInterface: client.Resource(k8s.Applications), resource: k8s.Applications.Resource, informer: ctrl.appInformer, ctrl: ctrl}
+	_, err = resClient.Namespace(TestNamespace).Patch(ctx, "test", types.MergePatchType, []byte(`{}`), metav1.PatchOptions{})
+	assert.NoError(t, err)
+
+	ctrl.tracer.sweep(time.Now().Add(traceIdleTimeout * 2))
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	if !assert.Contains(t, spans, "ProcessResource") {
+		return
+	}
+	root := spans["ProcessResource"]
+	for _, name := range []string{"RunTrigger", "Send", "PersistState"} {
+		if assert.Contains(t, spans, name) {
+			assert.Equal(t, root.SpanContext().SpanID(), spans[name].Parent().SpanID(), name)
+		}
+	}
+	for _, name := range []string{"Render", "Deliver"} {
+		if assert.Contains(t, spans, name) {
+			assert.Equal(t, spans["Send"].SpanContext().SpanID(), spans[name].Parent().SpanID(), name)
+		}
+	}
+	assert.False(t, root.EndTime().Before(spans["PersistState"].EndTime()))
+}
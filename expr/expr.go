@@ -1,6 +1,9 @@
 package expr
 
 import (
+	"context"
+	"sort"
+
 	"github.com/argoproj-labs/argocd-notifications/expr/repo"
 	"github.com/argoproj-labs/argocd-notifications/expr/strings"
 	"github.com/argoproj-labs/argocd-notifications/expr/sync"
@@ -19,10 +22,22 @@ func init() {
 }
 
 func register(namespace string, entry map[string]interface{}) {
-	helpers[namespace] = entry
+	helpers[namespace] = instrument(namespace, entry)
+}
+
+// Namespaces returns names of the variables which hold expression helpers
+func Namespaces() []string {
+	res := []string{"repo"}
+	for namespace := range helpers {
+		res = append(res, namespace)
+	}
+	sort.Strings(res)
+	return res
 }
 
-func Spawn(app *unstructured.Unstructured, argocdService argocd.Service, vars map[string]interface{}) map[string]interface{} {
+// Spawn returns the variables extended with expression helpers. The context is used by helpers which call Argo CD and
+// gitProviders by helpers which build web URLs of the application repository.
+func Spawn(ctx context.Context, app *unstructured.Unstructured, argocdService argocd.Service, gitProviders repo.GitProviders, vars map[string]interface{}) map[string]interface{} {
 	clone := make(map[string]interface{})
 	for k := range vars {
 		clone[k] = vars[k]
@@ -30,7 +45,7 @@ func Spawn(app *unstructured.Unstructured, argocdService argocd.Service, vars ma
 	for namespace, helper := range helpers {
 		clone[namespace] = helper
 	}
-	clone["repo"] = repo.NewExprs(argocdService, app)
+	clone["repo"] = instrument("repo", repo.NewExprs(ctx, argocdService, app, gitProviders))
 
 	return clone
 }
@@ -1,8 +1,12 @@
 package expr
 
 import (
+	"context"
 	"testing"
+	"time"
 
+	"github.com/antonmedv/expr"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -14,8 +18,34 @@ func TestExpr(t *testing.T) {
 	}
 
 	for _, ns := range namespaces {
-		helpers := Spawn(nil, nil, nil)
+		helpers := Spawn(context.Background(), nil, nil, nil, nil)
 		_, hasNamespace := helpers[ns]
 		assert.True(t, hasNamespace)
 	}
 }
+
+func TestExpr_CountsHelperPanics(t *testing.T) {
+	helpers := Spawn(context.Background(), nil, nil, nil, nil)
+	parse, ok := helpers["time"].(map[string]interface{})["Parse"].(func(string) time.Time)
+	if !assert.True(t, ok) {
+		return
+	}
+
+	counter := helperPanics.WithLabelValues("time.Parse")
+	before := testutil.ToFloat64(counter)
+	assert.NotPanics(t, func() {
+		parse("2021-11-17T10:00:00Z")
+	})
+	assert.Panics(t, func() {
+		parse("invalid")
+	})
+	assert.Equal(t, before+1, testutil.ToFloat64(counter))
+}
+
+func TestExpr_HelpersInConditions(t *testing.T) {
+	vars := Spawn(context.Background(), nil, nil, nil, map[string]interface{}{"name": "guestbook-prod"})
+
+	res, err := expr.Eval(`strings.Join(strings.Split(name, "-"), ".") == "guestbook.prod" && strings.RegexMatch("-prod$", name)`, vars)
+	assert.NoError(t, err)
+	assert.Equal(t, true, res)
+}
@@ -0,0 +1,47 @@
+package expr
+
+import (
+	"reflect"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var helperPanics = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "argocd_notifications_expr_helper_panics_total",
+		Help: "Number of expression helper calls which panicked.",
+	},
+	[]string{"helper"},
+)
+
+func init() {
+	prometheus.MustRegister(helperPanics)
+}
+
+// instrument returns helpers of the namespace which count panics of the helper functions
+func instrument(namespace string, entry map[string]interface{}) map[string]interface{} {
+	res := make(map[string]interface{}, len(entry))
+	for name, helper := range entry {
+		res[name] = instrumentFunc(namespace+"."+name, helper)
+	}
+	return res
+}
+
+func instrumentFunc(name string, helper interface{}) interface{} {
+	fn := reflect.ValueOf(helper)
+	if fn.Kind() != reflect.Func {
+		return helper
+	}
+	return reflect.MakeFunc(fn.Type(), func(args []reflect.Value) []reflect.Value {
+		defer func() {
+			if r := recover(); r != nil {
+				helperPanics.WithLabelValues(name).Inc()
+				panic(r)
+			}
+		}()
+		if fn.Type().IsVariadic() {
+			return fn.CallSlice(args)
+		}
+		return fn.Call(args)
+	}).Interface()
+}
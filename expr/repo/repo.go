@@ -2,62 +2,81 @@ package repo
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"regexp"
 	"strings"
 
 	"github.com/argoproj-labs/argocd-notifications/expr/shared"
 	"github.com/argoproj-labs/argocd-notifications/shared/argocd"
+	"github.com/argoproj-labs/argocd-notifications/shared/tracing"
 
-	"github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
 	"github.com/argoproj/notifications-engine/pkg/util/text"
 	giturls "github.com/whilp/git-urls"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 var (
 	gitSuffix = regexp.MustCompile(`\.git$`)
+	tracer    = otel.Tracer("github.com/argoproj-labs/argocd-notifications/expr/repo")
 )
 
-func getApplicationSource(obj *unstructured.Unstructured) (*v1alpha1.ApplicationSource, error) {
-	data, err := json.Marshal(obj)
+func getAppDetails(ctx context.Context, app *unstructured.Unstructured, source *Source, argocdService argocd.Service) (*shared.AppDetail, error) {
+	appSource, err := source.ApplicationSource()
 	if err != nil {
 		return nil, err
 	}
-	application := &v1alpha1.Application{}
-	err = json.Unmarshal(data, application)
+	ctx, span := startSpan(ctx, "GetAppDetails", app, attribute.Int("source", source.Index))
+	appDetail, err := argocdService.GetAppDetails(ctx, appSource)
+	tracing.End(span, err)
 	if err != nil {
 		return nil, err
 	}
-	return &application.Spec.Source, nil
+	return appDetail, nil
 }
 
-func getAppDetails(app *unstructured.Unstructured, argocdService argocd.Service) (*shared.AppDetail, error) {
-	appSource, err := getApplicationSource(app)
-	if err != nil {
-		return nil, err
+func getCommitMetadata(ctx context.Context, commitSHA string, app *unstructured.Unstructured, source *Source, argocdService argocd.Service) (*shared.CommitMetadata, error) {
+	if source.RepoURL == "" {
+		panic(errors.New("failed to get application source repo URL"))
 	}
-	appDetail, err := argocdService.GetAppDetails(context.Background(), appSource)
+	ctx, span := startSpan(ctx, "GetCommitMetadata", app, attribute.String("commit", commitSHA), attribute.Int("source", source.Index))
+	meta, err := argocdService.GetCommitMetadata(ctx, source.RepoURL, commitSHA)
+	tracing.End(span, err)
 	if err != nil {
 		return nil, err
 	}
-	return appDetail, nil
+	return meta, nil
 }
 
-func getCommitMetadata(commitSHA string, app *unstructured.Unstructured, argocdService argocd.Service) (*shared.CommitMetadata, error) {
-	repoURL, ok, err := unstructured.NestedString(app.Object, "spec", "source", "repoURL")
-	if err != nil {
-		return nil, err
-	}
-	if !ok {
-		panic(errors.New("failed to get application source repo URL"))
+// mustGetSource returns the application source with the given index or repository URL, or the default one if nil
+func mustGetSource(app *unstructured.Unstructured, indexOrRepoURL interface{}) *Source {
+	var source *Source
+	var err error
+	if indexOrRepoURL == nil {
+		source, err = getDefaultSource(app)
+	} else {
+		source, err = getSource(app, indexOrRepoURL)
 	}
-	meta, err := argocdService.GetCommitMetadata(context.Background(), repoURL, commitSHA)
 	if err != nil {
-		return nil, err
+		panic(err)
 	}
-	return meta, nil
+	return source
+}
+
+// getTargetRevision returns the revision the application source tracks or HEAD if the revision is not specified
+func getTargetRevision(source *Source) string {
+	if source.TargetRevision == "" {
+		return "HEAD"
+	}
+	return source.TargetRevision
+}
+
+// startSpan starts the span of the helper which calls Argo CD
+func startSpan(ctx context.Context, helper string, app *unstructured.Unstructured, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, attribute.String("app", app.GetNamespace()+"/"+app.GetName()))
+	return tracer.Start(ctx, "repo."+helper, trace.WithAttributes(attrs...))
 }
 
 func FullNameByRepoURL(rawURL string) string {
@@ -84,12 +103,46 @@ func repoURLToHTTPS(rawURL string) string {
 	return parsed.String()
 }
 
-func NewExprs(argocdService argocd.Service, app *unstructured.Unstructured) map[string]interface{} {
+// NewExprs returns helpers which query Argo CD about the application. Calls of Argo CD are traced as children of
+// the span of the given context. Web URLs of the repository are built using the provider detected by the repository
+// host or configured in providers.
+func NewExprs(ctx context.Context, argocdService argocd.Service, app *unstructured.Unstructured, providers GitProviders) map[string]interface{} {
 	return map[string]interface{}{
 		"RepoURLToHTTPS":    repoURLToHTTPS,
 		"FullNameByRepoURL": FullNameByRepoURL,
+		"CommitURL": func(sha string) string {
+			return getRepoWebURL(mustGetSource(app, nil), providers).commit(sha)
+		},
+		"CompareURL": func(from, to string) string {
+			return getRepoWebURL(mustGetSource(app, nil), providers).compare(from, to)
+		},
+		"TreeURL": func(path, revision string) string {
+			source := mustGetSource(app, nil)
+			if revision == "" {
+				revision = getTargetRevision(source)
+			}
+			return getRepoWebURL(source, providers).tree(path, revision)
+		},
+		"PullRequestURL": func(number interface{}) string {
+			return getRepoWebURL(mustGetSource(app, nil), providers).pullRequest(formatRequestNumber(number))
+		},
+		"Sources": func() []Source {
+			sources, err := getSources(app)
+			if err != nil {
+				panic(err)
+			}
+			return sources
+		},
 		"GetCommitMetadata": func(commitSHA string) interface{} {
-			meta, err := getCommitMetadata(commitSHA, app, argocdService)
+			meta, err := getCommitMetadata(ctx, commitSHA, app, mustGetSource(app, nil), argocdService)
+			if err != nil {
+				panic(err)
+			}
+
+			return *meta
+		},
+		"GetCommitMetadataForSource": func(indexOrRepoURL interface{}, commitSHA string) interface{} {
+			meta, err := getCommitMetadata(ctx, commitSHA, app, mustGetSource(app, indexOrRepoURL), argocdService)
 			if err != nil {
 				panic(err)
 			}
@@ -97,7 +150,15 @@ func NewExprs(argocdService argocd.Service, app *unstructured.Unstructured) map[
 			return *meta
 		},
 		"GetAppDetails": func() interface{} {
-			appDetails, err := getAppDetails(app, argocdService)
+			appDetails, err := getAppDetails(ctx, app, mustGetSource(app, nil), argocdService)
+			if err != nil {
+				panic(err)
+			}
+
+			return *appDetails
+		},
+		"GetAppDetailsForSource": func(indexOrRepoURL interface{}) interface{} {
+			appDetails, err := getAppDetails(ctx, app, mustGetSource(app, indexOrRepoURL), argocdService)
 			if err != nil {
 				panic(err)
 			}
@@ -42,8 +42,9 @@ func TestGetCommitMetadata(t *testing.T) {
 
 	argocdService := mocks.NewMockService(ctrl)
 	expectedMeta := &shared.CommitMetadata{Message: "hello"}
-	argocdService.EXPECT().GetCommitMetadata(context.Background(), "http://myrepo-url.git", "abc").Return(expectedMeta, nil)
-	commitMeta, err := getCommitMetadata("abc", NewApp("guestbook", WithRepoURL("http://myrepo-url.git")), argocdService)
+	argocdService.EXPECT().GetCommitMetadata(gomock.Any(), "http://myrepo-url.git", "abc").Return(expectedMeta, nil)
+	app := NewApp("guestbook", WithRepoURL("http://myrepo-url.git"))
+	commitMeta, err := getCommitMetadata(context.Background(), "abc", app, mustGetSource(app, nil), argocdService)
 
 	if !assert.NoError(t, err) {
 		return
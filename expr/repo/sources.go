@@ -0,0 +1,119 @@
+package repo
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Source is the application source paired with the revision the source was synced to
+type Source struct {
+	Index          int
+	RepoURL        string
+	Path           string
+	Chart          string
+	TargetRevision string
+	Ref            string
+	// Revision is the synced revision of the source from status.sync.revision(s)
+	Revision string
+
+	source map[string]interface{}
+}
+
+// ApplicationSource converts the source to the Argo CD type
+func (s Source) ApplicationSource() (*v1alpha1.ApplicationSource, error) {
+	data, err := json.Marshal(s.source)
+	if err != nil {
+		return nil, err
+	}
+	res := &v1alpha1.ApplicationSource{}
+	if err := json.Unmarshal(data, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// getSources returns sources of both single-source (spec.source) and multi-source (spec.sources) applications.
+// Multi-source applications are paired with status.sync.revisions by index.
+func getSources(app *unstructured.Unstructured) ([]Source, error) {
+	sources, _, err := unstructured.NestedSlice(app.Object, "spec", "sources")
+	if err != nil {
+		return nil, err
+	}
+	// Argo CD ignores spec.source if spec.sources is specified
+	if len(sources) == 0 {
+		source, ok, err := unstructured.NestedMap(app.Object, "spec", "source")
+		if err != nil || !ok {
+			return nil, err
+		}
+		revision, _, _ := unstructured.NestedString(app.Object, "status", "sync", "revision")
+		return []Source{newSource(0, source, revision)}, nil
+	}
+	revisions, _, err := unstructured.NestedStringSlice(app.Object, "status", "sync", "revisions")
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Source, len(sources))
+	for i := range sources {
+		source, ok := sources[i].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("source %d of application '%s' is not an object", i, app.GetName())
+		}
+		revision := ""
+		if i < len(revisions) {
+			revision = revisions[i]
+		}
+		res[i] = newSource(i, source, revision)
+	}
+	return res, nil
+}
+
+func newSource(index int, source map[string]interface{}, revision string) Source {
+	res := Source{Index: index, Revision: revision, source: source}
+	res.RepoURL, _, _ = unstructured.NestedString(source, "repoURL")
+	res.Path, _, _ = unstructured.NestedString(source, "path")
+	res.Chart, _, _ = unstructured.NestedString(source, "chart")
+	res.TargetRevision, _, _ = unstructured.NestedString(source, "targetRevision")
+	res.Ref, _, _ = unstructured.NestedString(source, "ref")
+	return res
+}
+
+// getSource returns the application source with the given index or repository URL
+func getSource(app *unstructured.Unstructured, indexOrRepoURL interface{}) (*Source, error) {
+	sources, err := getSources(app)
+	if err != nil {
+		return nil, err
+	}
+	if index, ok := indexOrRepoURL.(int64); ok {
+		indexOrRepoURL = int(index)
+	}
+	switch v := indexOrRepoURL.(type) {
+	case int:
+		if v < 0 || v >= len(sources) {
+			return nil, fmt.Errorf("application '%s' has no source with index %d", app.GetName(), v)
+		}
+		return &sources[v], nil
+	case string:
+		for i := range sources {
+			if sources[i].RepoURL == v {
+				return &sources[i], nil
+			}
+		}
+		return nil, fmt.Errorf("application '%s' has no source with repo URL %s", app.GetName(), v)
+	}
+	return nil, fmt.Errorf("expected source index or repo URL but got %T", indexOrRepoURL)
+}
+
+// getDefaultSource returns the source of the single-source application or the first source of the multi-source one
+func getDefaultSource(app *unstructured.Unstructured) (*Source, error) {
+	sources, err := getSources(app)
+	if err != nil {
+		return nil, err
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("failed to get application source repo URL")
+	}
+	return &sources[0], nil
+}
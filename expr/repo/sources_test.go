@@ -0,0 +1,134 @@
+package repo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/argoproj-labs/argocd-notifications/expr/shared"
+	"github.com/argoproj-labs/argocd-notifications/shared/argocd/mocks"
+	. "github.com/argoproj-labs/argocd-notifications/testing"
+)
+
+func withSources(sources ...map[string]interface{}) func(app *unstructured.Unstructured) {
+	return func(app *unstructured.Unstructured) {
+		var items []interface{}
+		for i := range sources {
+			items = append(items, sources[i])
+		}
+		_ = unstructured.SetNestedSlice(app.Object, items, "spec", "sources")
+	}
+}
+
+func withSyncRevisions(revisions ...string) func(app *unstructured.Unstructured) {
+	return func(app *unstructured.Unstructured) {
+		_ = unstructured.SetNestedStringSlice(app.Object, revisions, "status", "sync", "revisions")
+	}
+}
+
+func newMultiSourceApp() *unstructured.Unstructured {
+	return NewApp("guestbook",
+		withSources(map[string]interface{}{
+			"repoURL":        "https://github.com/argoproj/argocd-example-apps.git",
+			"path":           "guestbook",
+			"targetRevision": "master",
+		}, map[string]interface{}{
+			"repoURL":        "https://github.com/argoproj/values.git",
+			"targetRevision": "v1.0.0",
+			"ref":            "values",
+		}),
+		withSyncRevisions("abc", "def"))
+}
+
+func TestGetSources(t *testing.T) {
+	sources, err := getSources(newMultiSourceApp())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	if assert.Len(t, sources, 2) {
+		assert.Equal(t, "https://github.com/argoproj/argocd-example-apps.git", sources[0].RepoURL)
+		assert.Equal(t, "guestbook", sources[0].Path)
+		assert.Equal(t, "abc", sources[0].Revision)
+		assert.Equal(t, 1, sources[1].Index)
+		assert.Equal(t, "values", sources[1].Ref)
+		assert.Equal(t, "def", sources[1].Revision)
+	}
+}
+
+func TestGetSources_SingleSource(t *testing.T) {
+	app := NewApp("guestbook", WithRepoURL("https://github.com/argoproj/argocd-example-apps.git"))
+	_ = unstructured.SetNestedField(app.Object, "abc", "status", "sync", "revision")
+
+	sources, err := getSources(app)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, []Source{{
+		RepoURL:  "https://github.com/argoproj/argocd-example-apps.git",
+		Revision: "abc",
+		source:   map[string]interface{}{"repoURL": "https://github.com/argoproj/argocd-example-apps.git"},
+	}}, sources)
+}
+
+func TestGetSources_SourcesTakePrecedence(t *testing.T) {
+	app := newMultiSourceApp()
+	_ = unstructured.SetNestedField(app.Object, map[string]interface{}{"repoURL": "https://github.com/argoproj/ignored.git"}, "spec", "source")
+
+	sources, err := getSources(app)
+	if !assert.NoError(t, err) {
+		return
+	}
+	if assert.Len(t, sources, 2) {
+		assert.Equal(t, "https://github.com/argoproj/argocd-example-apps.git", sources[0].RepoURL)
+	}
+}
+
+func TestGetSource(t *testing.T) {
+	app := newMultiSourceApp()
+
+	source, err := getSource(app, 1)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "https://github.com/argoproj/values.git", source.RepoURL)
+	}
+	source, err = getSource(app, "https://github.com/argoproj/values.git")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1, source.Index)
+	}
+	_, err = getSource(app, 2)
+	assert.Error(t, err)
+	_, err = getSource(app, "https://github.com/argoproj/unknown.git")
+	assert.Error(t, err)
+	_, err = getSource(app, 1.5)
+	assert.Error(t, err)
+}
+
+func TestSourceHelpers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	argocdService := mocks.NewMockService(ctrl)
+	argocdService.EXPECT().GetCommitMetadata(gomock.Any(), "https://github.com/argoproj/values.git", "def").Return(&shared.CommitMetadata{Message: "values"}, nil)
+	argocdService.EXPECT().GetCommitMetadata(gomock.Any(), "https://github.com/argoproj/argocd-example-apps.git", "abc").Return(&shared.CommitMetadata{Message: "guestbook"}, nil)
+	argocdService.EXPECT().GetAppDetails(gomock.Any(), &v1alpha1.ApplicationSource{
+		RepoURL:        "https://github.com/argoproj/argocd-example-apps.git",
+		Path:           "guestbook",
+		TargetRevision: "master",
+	}).Return(&shared.AppDetail{Type: "Directory"}, nil)
+	exprs := NewExprs(context.Background(), argocdService, newMultiSourceApp(), nil)
+
+	meta := exprs["GetCommitMetadataForSource"].(func(interface{}, string) interface{})(1, "def")
+	assert.Equal(t, "values", meta.(shared.CommitMetadata).Message)
+	meta = exprs["GetCommitMetadata"].(func(string) interface{})("abc")
+	assert.Equal(t, "guestbook", meta.(shared.CommitMetadata).Message)
+	details := exprs["GetAppDetailsForSource"].(func(interface{}) interface{})("https://github.com/argoproj/argocd-example-apps.git")
+	assert.Equal(t, "Directory", details.(shared.AppDetail).Type)
+	assert.Equal(t, "https://github.com/argoproj/argocd-example-apps/commit/abc",
+		exprs["CommitURL"].(func(string) string)("abc"))
+	assert.Len(t, exprs["Sources"].(func() []Source)(), 2)
+}
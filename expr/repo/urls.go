@@ -0,0 +1,211 @@
+package repo
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/argoproj/notifications-engine/pkg/util/text"
+	giturls "github.com/whilp/git-urls"
+)
+
+// Provider is the Git hosting provider which defines the layout of repository web URLs
+type Provider string
+
+const (
+	ProviderGitHub          Provider = "github"
+	ProviderGitLab          Provider = "gitlab"
+	ProviderGitea           Provider = "gitea"
+	ProviderBitbucket       Provider = "bitbucket"
+	ProviderBitbucketServer Provider = "bitbucketServer"
+	ProviderAzureDevOps     Provider = "azureDevOps"
+)
+
+var (
+	knownProviders = map[string]Provider{
+		"github.com":        ProviderGitHub,
+		"gitlab.com":        ProviderGitLab,
+		"gitea.com":         ProviderGitea,
+		"bitbucket.org":     ProviderBitbucket,
+		"dev.azure.com":     ProviderAzureDevOps,
+		"ssh.dev.azure.com": ProviderAzureDevOps,
+	}
+	commitSHA     = regexp.MustCompile(`^[0-9a-fA-F]{7,40}$`)
+	requestNumber = regexp.MustCompile(`^[0-9]+$`)
+)
+
+func (p Provider) validate() error {
+	switch p {
+	case ProviderGitHub, ProviderGitLab, ProviderGitea, ProviderBitbucket, ProviderBitbucketServer, ProviderAzureDevOps:
+		return nil
+	}
+	return fmt.Errorf("unknown git provider '%s'", p)
+}
+
+// GitProviders maps hosts of self-hosted Git servers to their providers
+type GitProviders map[string]Provider
+
+// Validate returns an error if any of the hosts is mapped to an unknown provider
+func (p GitProviders) Validate() error {
+	for host, provider := range p {
+		if err := provider.validate(); err != nil {
+			return fmt.Errorf("host %s: %v", host, err)
+		}
+	}
+	return nil
+}
+
+// get returns the provider of the host. Hosts of the public providers are detected automatically.
+func (p GitProviders) get(host string) (Provider, error) {
+	if provider, ok := p[host]; ok {
+		return provider, nil
+	}
+	if provider, ok := knownProviders[host]; ok {
+		return provider, nil
+	}
+	return "", fmt.Errorf("unable to detect git provider of host %s, configure it in the gitProviders key of the notifications ConfigMap", host)
+}
+
+// repoWebURL holds the web URL of the repository and the provider which defines the layout of the repository pages
+type repoWebURL struct {
+	provider Provider
+	base     string
+}
+
+// newRepoWebURL converts the repository URL used by Argo CD to the web URL of the repository
+func newRepoWebURL(rawURL string, providers GitProviders) (*repoWebURL, error) {
+	parsed, err := giturls.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	host := parsed.Hostname()
+	provider, err := providers.get(host)
+	if err != nil {
+		return nil, err
+	}
+	parts := text.SplitRemoveEmpty(gitSuffix.ReplaceAllString(parsed.Path, ""), "/")
+	switch provider {
+	case ProviderAzureDevOps:
+		// git@ssh.dev.azure.com:v3/org/project/repo and https://dev.azure.com/org/project/_git/repo
+		if len(parts) == 4 && parts[0] == "v3" {
+			parts = []string{parts[1], parts[2], "_git", parts[3]}
+		}
+		if host == "ssh.dev.azure.com" {
+			host = "dev.azure.com"
+		}
+		if len(parts) != 4 || parts[2] != "_git" {
+			return nil, fmt.Errorf("unexpected Azure DevOps repository URL %s", rawURL)
+		}
+	case ProviderBitbucketServer:
+		// https://host/scm/project/repo and ssh://git@host:7999/project/repo
+		if len(parts) == 3 && parts[0] == "scm" {
+			parts = parts[1:]
+		}
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("unexpected Bitbucket Server repository URL %s", rawURL)
+		}
+		parts = []string{"projects", parts[0], "repos", parts[1]}
+	case ProviderGitLab:
+		// GitLab repositories might belong to subgroups
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("unexpected repository URL %s", rawURL)
+		}
+	default:
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("unexpected repository URL %s", rawURL)
+		}
+	}
+	if parsed.Scheme == "https" || parsed.Scheme == "http" {
+		// keep the custom port of the web server but not the SSH one
+		host = parsed.Host
+	}
+	return &repoWebURL{provider: provider, base: "https://" + host + "/" + strings.Join(parts, "/")}, nil
+}
+
+func (u *repoWebURL) commit(sha string) string {
+	switch u.provider {
+	case ProviderGitLab:
+		return u.base + "/-/commit/" + sha
+	case ProviderBitbucket, ProviderBitbucketServer:
+		return u.base + "/commits/" + sha
+	}
+	return u.base + "/commit/" + sha
+}
+
+func (u *repoWebURL) compare(from, to string) string {
+	switch u.provider {
+	case ProviderGitLab:
+		return u.base + "/-/compare/" + from + "..." + to
+	case ProviderBitbucket:
+		return u.base + "/branches/compare/" + to + "%0D" + from
+	case ProviderBitbucketServer:
+		return u.base + "/compare/diff?" + url.Values{"sourceBranch": {to}, "targetBranch": {from}}.Encode()
+	case ProviderAzureDevOps:
+		return u.base + "/branchCompare?" + url.Values{"baseVersion": {azureVersion(from)}, "targetVersion": {azureVersion(to)}}.Encode()
+	}
+	return u.base + "/compare/" + from + "..." + to
+}
+
+func (u *repoWebURL) tree(path, revision string) string {
+	path = strings.Trim(path, "/")
+	switch u.provider {
+	case ProviderGitLab:
+		return strings.TrimSuffix(u.base+"/-/tree/"+revision+"/"+path, "/")
+	case ProviderGitea:
+		if commitSHA.MatchString(revision) {
+			return strings.TrimSuffix(u.base+"/src/commit/"+revision+"/"+path, "/")
+		}
+		return strings.TrimSuffix(u.base+"/src/branch/"+revision+"/"+path, "/")
+	case ProviderBitbucket:
+		return strings.TrimSuffix(u.base+"/src/"+revision+"/"+path, "/")
+	case ProviderBitbucketServer:
+		return strings.TrimSuffix(u.base+"/browse/"+path, "/") + "?" + url.Values{"at": {revision}}.Encode()
+	case ProviderAzureDevOps:
+		return u.base + "?" + url.Values{"path": {"/" + path}, "version": {azureVersion(revision)}}.Encode()
+	}
+	return strings.TrimSuffix(u.base+"/tree/"+revision+"/"+path, "/")
+}
+
+func (u *repoWebURL) pullRequest(number string) string {
+	switch u.provider {
+	case ProviderGitLab:
+		return u.base + "/-/merge_requests/" + number
+	case ProviderGitea:
+		return u.base + "/pulls/" + number
+	case ProviderBitbucket, ProviderBitbucketServer:
+		return u.base + "/pull-requests/" + number
+	case ProviderAzureDevOps:
+		return u.base + "/pullrequest/" + number
+	}
+	return u.base + "/pull/" + number
+}
+
+// azureVersion returns the Azure DevOps version descriptor of the commit SHA or the branch name
+func azureVersion(revision string) string {
+	if commitSHA.MatchString(revision) {
+		return "GC" + revision
+	}
+	return "GB" + revision
+}
+
+// getRepoWebURL returns the web URL of the application source repository
+func getRepoWebURL(source *Source, providers GitProviders) *repoWebURL {
+	if source.RepoURL == "" {
+		panic(fmt.Errorf("failed to get application source repo URL"))
+	}
+	res, err := newRepoWebURL(source.RepoURL, providers)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+// formatRequestNumber accepts numbers of pull requests passed both as numbers and strings
+func formatRequestNumber(number interface{}) string {
+	res := fmt.Sprint(number)
+	if !requestNumber.MatchString(res) {
+		panic(fmt.Errorf("invalid pull request number '%v'", number))
+	}
+	return res
+}
@@ -0,0 +1,103 @@
+package repo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/argoproj-labs/argocd-notifications/testing"
+)
+
+func TestRepoWebURL(t *testing.T) {
+	providers := GitProviders{
+		"git.example.com":       ProviderGitLab,
+		"bitbucket.example.com": ProviderBitbucketServer,
+		"gitea.example.com":     ProviderGitea,
+	}
+	testCases := []struct {
+		repoURL     string
+		commit      string
+		compare     string
+		tree        string
+		pullRequest string
+	}{{
+		repoURL:     "git@github.com:argoproj/argo-cd.git",
+		commit:      "https://github.com/argoproj/argo-cd/commit/abc1234",
+		compare:     "https://github.com/argoproj/argo-cd/compare/v1.0.0...v1.1.0",
+		tree:        "https://github.com/argoproj/argo-cd/tree/master/manifests/base",
+		pullRequest: "https://github.com/argoproj/argo-cd/pull/42",
+	}, {
+		repoURL:     "https://gitlab.com/argoproj/labs/argo-cd.git",
+		commit:      "https://gitlab.com/argoproj/labs/argo-cd/-/commit/abc1234",
+		compare:     "https://gitlab.com/argoproj/labs/argo-cd/-/compare/v1.0.0...v1.1.0",
+		tree:        "https://gitlab.com/argoproj/labs/argo-cd/-/tree/master/manifests/base",
+		pullRequest: "https://gitlab.com/argoproj/labs/argo-cd/-/merge_requests/42",
+	}, {
+		repoURL:     "https://user@bitbucket.org/argoproj/argo-cd.git",
+		commit:      "https://bitbucket.org/argoproj/argo-cd/commits/abc1234",
+		compare:     "https://bitbucket.org/argoproj/argo-cd/branches/compare/v1.1.0%0Dv1.0.0",
+		tree:        "https://bitbucket.org/argoproj/argo-cd/src/master/manifests/base",
+		pullRequest: "https://bitbucket.org/argoproj/argo-cd/pull-requests/42",
+	}, {
+		repoURL:     "git@ssh.dev.azure.com:v3/argoproj/tools/argo-cd",
+		commit:      "https://dev.azure.com/argoproj/tools/_git/argo-cd/commit/abc1234",
+		compare:     "https://dev.azure.com/argoproj/tools/_git/argo-cd/branchCompare?baseVersion=GBv1.0.0&targetVersion=GBv1.1.0",
+		tree:        "https://dev.azure.com/argoproj/tools/_git/argo-cd?path=%2Fmanifests%2Fbase&version=GBmaster",
+		pullRequest: "https://dev.azure.com/argoproj/tools/_git/argo-cd/pullrequest/42",
+	}, {
+		repoURL:     "ssh://git@git.example.com:2222/argoproj/argo-cd.git",
+		commit:      "https://git.example.com/argoproj/argo-cd/-/commit/abc1234",
+		compare:     "https://git.example.com/argoproj/argo-cd/-/compare/v1.0.0...v1.1.0",
+		tree:        "https://git.example.com/argoproj/argo-cd/-/tree/master/manifests/base",
+		pullRequest: "https://git.example.com/argoproj/argo-cd/-/merge_requests/42",
+	}, {
+		repoURL:     "https://bitbucket.example.com/scm/argoproj/argo-cd.git",
+		commit:      "https://bitbucket.example.com/projects/argoproj/repos/argo-cd/commits/abc1234",
+		compare:     "https://bitbucket.example.com/projects/argoproj/repos/argo-cd/compare/diff?sourceBranch=v1.1.0&targetBranch=v1.0.0",
+		tree:        "https://bitbucket.example.com/projects/argoproj/repos/argo-cd/browse/manifests/base?at=master",
+		pullRequest: "https://bitbucket.example.com/projects/argoproj/repos/argo-cd/pull-requests/42",
+	}, {
+		repoURL:     "https://gitea.example.com:3000/argoproj/argo-cd.git",
+		commit:      "https://gitea.example.com:3000/argoproj/argo-cd/commit/abc1234",
+		compare:     "https://gitea.example.com:3000/argoproj/argo-cd/compare/v1.0.0...v1.1.0",
+		tree:        "https://gitea.example.com:3000/argoproj/argo-cd/src/branch/master/manifests/base",
+		pullRequest: "https://gitea.example.com:3000/argoproj/argo-cd/pulls/42",
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.repoURL, func(t *testing.T) {
+			u, err := newRepoWebURL(tc.repoURL, providers)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tc.commit, u.commit("abc1234"))
+			assert.Equal(t, tc.compare, u.compare("v1.0.0", "v1.1.0"))
+			assert.Equal(t, tc.tree, u.tree("/manifests/base", "master"))
+			assert.Equal(t, tc.pullRequest, u.pullRequest("42"))
+		})
+	}
+}
+
+func TestRepoWebURL_UnknownProvider(t *testing.T) {
+	_, err := newRepoWebURL("https://git.example.com/argoproj/argo-cd.git", nil)
+	assert.Error(t, err)
+}
+
+func TestURLHelpers(t *testing.T) {
+	app := NewApp("guestbook", WithRepoURL("https://github.com/argoproj/argocd-example-apps.git"))
+	exprs := NewExprs(context.Background(), nil, app, nil)
+
+	assert.Equal(t, "https://github.com/argoproj/argocd-example-apps/tree/HEAD/guestbook",
+		exprs["TreeURL"].(func(string, string) string)("guestbook", ""))
+	assert.Equal(t, "https://github.com/argoproj/argocd-example-apps/pull/42",
+		exprs["PullRequestURL"].(func(interface{}) string)(42))
+	assert.Equal(t, "https://github.com/argoproj/argocd-example-apps/pull/42",
+		exprs["PullRequestURL"].(func(interface{}) string)("42"))
+	assert.Panics(t, func() {
+		exprs["PullRequestURL"].(func(interface{}) string)("#42")
+	})
+	assert.Panics(t, func() {
+		NewExprs(context.Background(), nil, NewApp("guestbook"), nil)["CommitURL"].(func(string) string)("abc1234")
+	})
+}
@@ -1,15 +1,64 @@
 package strings
 
 import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"regexp"
 	"strings"
+	"sync"
+	"unicode"
+)
+
+var (
+	// regexps caches compiled patterns since the same expression is evaluated for every resource
+	regexps sync.Map
+
+	slackReplacer    = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	markdownReplacer = newEscapeReplacer("\\`*_{}[]()<>#+-.!|~")
 )
 
 func NewExprs() map[string]interface{} {
 	return map[string]interface{}{
-		"ReplaceAll": replaceAll,
-		"ToUpper":    toUpper,
-		"ToLower":    toLower,
+		"ReplaceAll":     replaceAll,
+		"ToUpper":        toUpper,
+		"ToLower":        toLower,
+		"Split":          split,
+		"Join":           join,
+		"Contains":       contains,
+		"HasPrefix":      hasPrefix,
+		"HasSuffix":      hasSuffix,
+		"TrimSpace":      trimSpace,
+		"Truncate":       truncate,
+		"RegexMatch":     regexMatch,
+		"RegexReplace":   regexReplace,
+		"RegexFind":      regexFind,
+		"Title":          title,
+		"EscapeSlack":    escapeSlack,
+		"EscapeMarkdown": escapeMarkdown,
+		"EscapeHTML":     escapeHTML,
+		"EscapeJSON":     escapeJSON,
+	}
+}
+
+func newEscapeReplacer(chars string) *strings.Replacer {
+	var oldnew []string
+	for _, c := range chars {
+		oldnew = append(oldnew, string(c), "\\"+string(c))
 	}
+	return strings.NewReplacer(oldnew...)
+}
+
+func getRegexp(pattern string) *regexp.Regexp {
+	if res, ok := regexps.Load(pattern); ok {
+		return res.(*regexp.Regexp)
+	}
+	res, err := regexp.Compile(pattern)
+	if err != nil {
+		panic(err)
+	}
+	regexps.Store(pattern, res)
+	return res
 }
 
 func replaceAll(s, old, new string) string {
@@ -23,3 +72,99 @@ func toUpper(s string) string {
 func toLower(s string) string {
 	return strings.ToLower(s)
 }
+
+func split(s, sep string) []string {
+	return strings.Split(s, sep)
+}
+
+// join accepts both string slices returned by helpers and arrays of the expressions
+func join(elems interface{}, sep string) string {
+	switch v := elems.(type) {
+	case []string:
+		return strings.Join(v, sep)
+	case []interface{}:
+		items := make([]string, len(v))
+		for i := range v {
+			items[i] = fmt.Sprint(v[i])
+		}
+		return strings.Join(items, sep)
+	}
+	panic(fmt.Errorf("expected array but got %T", elems))
+}
+
+func contains(s, substr string) bool {
+	return strings.Contains(s, substr)
+}
+
+func hasPrefix(s, prefix string) bool {
+	return strings.HasPrefix(s, prefix)
+}
+
+func hasSuffix(s, suffix string) bool {
+	return strings.HasSuffix(s, suffix)
+}
+
+func trimSpace(s string) string {
+	return strings.TrimSpace(s)
+}
+
+// truncate shortens the string to at most the given number of characters replacing the tail with "..."
+func truncate(s string, length int) string {
+	if length < 0 {
+		panic(fmt.Errorf("length must not be negative but got %d", length))
+	}
+	runes := []rune(s)
+	if len(runes) <= length {
+		return s
+	}
+	if length <= 3 {
+		return string(runes[:length])
+	}
+	return string(runes[:length-3]) + "..."
+}
+
+func regexMatch(pattern, s string) bool {
+	return getRegexp(pattern).MatchString(s)
+}
+
+func regexReplace(pattern, s, repl string) string {
+	return getRegexp(pattern).ReplaceAllString(s, repl)
+}
+
+// regexFind returns the first match of the pattern or the empty string if there is no match
+func regexFind(pattern, s string) string {
+	return getRegexp(pattern).FindString(s)
+}
+
+// title upper-cases the first letter of every word
+func title(s string) string {
+	res := []rune(s)
+	for i := range res {
+		if i == 0 || unicode.IsSpace(res[i-1]) || res[i-1] == '-' || res[i-1] == '_' {
+			res[i] = unicode.ToTitle(res[i])
+		}
+	}
+	return string(res)
+}
+
+// escapeSlack escapes control characters of Slack mrkdwn
+func escapeSlack(s string) string {
+	return slackReplacer.Replace(s)
+}
+
+func escapeMarkdown(s string) string {
+	return markdownReplacer.Replace(s)
+}
+
+func escapeHTML(s string) string {
+	return html.EscapeString(s)
+}
+
+// escapeJSON escapes the string so that it can be placed between quotes of a JSON string
+func escapeJSON(s string) string {
+	data, err := json.Marshal(s)
+	if err != nil {
+		panic(err)
+	}
+	return string(data[1 : len(data)-1])
+}
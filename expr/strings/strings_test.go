@@ -12,6 +12,21 @@ func TestNewExprs(t *testing.T) {
 		"ReplaceAll",
 		"ToUpper",
 		"ToLower",
+		"Split",
+		"Join",
+		"Contains",
+		"HasPrefix",
+		"HasSuffix",
+		"TrimSpace",
+		"Truncate",
+		"RegexMatch",
+		"RegexReplace",
+		"RegexFind",
+		"Title",
+		"EscapeSlack",
+		"EscapeMarkdown",
+		"EscapeHTML",
+		"EscapeJSON",
 	}
 
 	for _, fn := range funcs {
@@ -63,3 +78,53 @@ func TestUpperAndLower(t *testing.T) {
 	}
 
 }
+
+func TestSplitAndJoin(t *testing.T) {
+	assert.Equal(t, []string{"a", "b", "c"}, split("a,b,c", ","))
+	assert.Equal(t, "a, b, c", join([]string{"a", "b", "c"}, ", "))
+	assert.Equal(t, "a-1-true", join([]interface{}{"a", 1, true}, "-"))
+	assert.Panics(t, func() {
+		join("abc", ",")
+	})
+}
+
+func TestPredicates(t *testing.T) {
+	assert.True(t, contains("guestbook-prod", "prod"))
+	assert.False(t, contains("guestbook-prod", "dev"))
+	assert.True(t, hasPrefix("guestbook-prod", "guestbook"))
+	assert.True(t, hasSuffix("guestbook-prod", "-prod"))
+	assert.Equal(t, "guestbook", trimSpace("  guestbook\n"))
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "guestbook", truncate("guestbook", 9))
+	assert.Equal(t, "gues...", truncate("guestbook", 7))
+	assert.Equal(t, "gu", truncate("guestbook", 2))
+	assert.Equal(t, "привет...", truncate("привет мир", 9))
+	assert.Panics(t, func() {
+		truncate("guestbook", -1)
+	})
+}
+
+func TestRegex(t *testing.T) {
+	assert.True(t, regexMatch(`^guestbook-\d+$`, "guestbook-42"))
+	assert.False(t, regexMatch(`^guestbook-\d+$`, "guestbook-prod"))
+	assert.Equal(t, "guestbook-N", regexReplace(`\d+`, "guestbook-42", "N"))
+	assert.Equal(t, "v1.2.3", regexFind(`v\d+\.\d+\.\d+`, "image:v1.2.3-rc"))
+	assert.Equal(t, "", regexFind(`v\d+`, "image:latest"))
+	assert.Panics(t, func() {
+		regexMatch(`(`, "guestbook")
+	})
+}
+
+func TestTitle(t *testing.T) {
+	assert.Equal(t, "Sync Succeeded", title("sync succeeded"))
+	assert.Equal(t, "Guestbook-Prod", title("guestbook-prod"))
+}
+
+func TestEscape(t *testing.T) {
+	assert.Equal(t, "a &lt;b&gt; &amp; c", escapeSlack("a <b> & c"))
+	assert.Equal(t, `\*bold\* \[link\]\(url\)`, escapeMarkdown("*bold* [link](url)"))
+	assert.Equal(t, "&lt;b&gt;&#34;x&#34; &amp; y&lt;/b&gt;", escapeHTML(`<b>"x" & y</b>`))
+	assert.Equal(t, `line \"1\"\nline 2`, escapeJSON("line \"1\"\nline 2"))
+}
@@ -1,13 +1,43 @@
 package time
 
 import (
+	"fmt"
+	"strings"
 	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
+var layouts = map[string]string{
+	"ANSIC":       time.ANSIC,
+	"UnixDate":    time.UnixDate,
+	"RubyDate":    time.RubyDate,
+	"RFC822":      time.RFC822,
+	"RFC822Z":     time.RFC822Z,
+	"RFC850":      time.RFC850,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"Kitchen":     time.Kitchen,
+	"Stamp":       time.Stamp,
+	"DateTime":    "2006-01-02 15:04:05",
+	"DateOnly":    "2006-01-02",
+	"TimeOnly":    "15:04:05",
+}
+
 func NewExprs() map[string]interface{} {
 	return map[string]interface{}{
-		"Parse": parse,
-		"Now":   now,
+		"Parse":             parse,
+		"Now":               now,
+		"ParseLayout":       parseLayout,
+		"ParseDuration":     parseDuration,
+		"Format":            format,
+		"InZone":            inZone,
+		"Since":             since,
+		"Until":             until,
+		"HumanizeDuration":  humanizeDuration,
+		"OperationDuration": operationDuration,
 	}
 }
 
@@ -22,3 +52,124 @@ func parse(timestamp string) time.Time {
 func now() time.Time {
 	return time.Now()
 }
+
+// getLayout returns the layout with the given name, e.g. RFC1123, or the layout itself if it is not a known name
+func getLayout(layout string) string {
+	if res, ok := layouts[layout]; ok {
+		return res
+	}
+	return layout
+}
+
+func parseLayout(layout string, value string) time.Time {
+	res, err := time.Parse(getLayout(layout), value)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+func parseDuration(value string) time.Duration {
+	res, err := time.ParseDuration(value)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+// toTime converts time.Time and RFC3339 timestamps to time.Time
+func toTime(value interface{}) time.Time {
+	switch v := value.(type) {
+	case time.Time:
+		return v
+	case *time.Time:
+		if v == nil {
+			panic(fmt.Errorf("time is nil"))
+		}
+		return *v
+	case string:
+		return parse(v)
+	}
+	panic(fmt.Errorf("expected time or RFC3339 timestamp but got %T", value))
+}
+
+// toDuration converts time.Duration and duration strings such as 1h30m to time.Duration
+func toDuration(value interface{}) time.Duration {
+	switch v := value.(type) {
+	case time.Duration:
+		return v
+	case string:
+		return parseDuration(v)
+	}
+	panic(fmt.Errorf("expected duration but got %T", value))
+}
+
+func format(value interface{}, layout string) string {
+	return toTime(value).Format(getLayout(layout))
+}
+
+func inZone(value interface{}, zone string) time.Time {
+	location, err := time.LoadLocation(zone)
+	if err != nil {
+		panic(err)
+	}
+	return toTime(value).In(location)
+}
+
+func since(value interface{}) time.Duration {
+	return time.Since(toTime(value))
+}
+
+func until(value interface{}) time.Duration {
+	return time.Until(toTime(value))
+}
+
+// humanizeDuration formats the duration using two most significant units, e.g. 2d3h or 5m10s
+func humanizeDuration(value interface{}) string {
+	d := toDuration(value)
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+	units := []struct {
+		suffix string
+		size   time.Duration
+	}{
+		{"d", 24 * time.Hour},
+		{"h", time.Hour},
+		{"m", time.Minute},
+		{"s", time.Second},
+	}
+	var parts []string
+	for _, unit := range units {
+		n := d / unit.size
+		d -= n * unit.size
+		if n > 0 {
+			parts = append(parts, fmt.Sprintf("%d%s", n, unit.suffix))
+		} else if len(parts) > 0 {
+			break
+		}
+		if len(parts) == 2 {
+			break
+		}
+	}
+	if len(parts) == 0 {
+		return "0s"
+	}
+	return sign + strings.Join(parts, "")
+}
+
+// operationDuration returns how long the operation of the application took or has been running so far
+func operationDuration(app map[string]interface{}) time.Duration {
+	startedAt, ok, _ := unstructured.NestedString(app, "status", "operationState", "startedAt")
+	if !ok {
+		un := unstructured.Unstructured{Object: app}
+		panic(fmt.Errorf("application '%s' has no operation start time", un.GetName()))
+	}
+	finishedAt, ok, _ := unstructured.NestedString(app, "status", "operationState", "finishedAt")
+	if !ok {
+		return since(startedAt)
+	}
+	return parse(finishedAt).Sub(parse(startedAt))
+}
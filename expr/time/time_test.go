@@ -2,6 +2,7 @@ package time
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -10,6 +11,14 @@ func TestNewTimeExprs(t *testing.T) {
 	funcs := []string{
 		"Parse",
 		"Now",
+		"ParseLayout",
+		"ParseDuration",
+		"Format",
+		"InZone",
+		"Since",
+		"Until",
+		"HumanizeDuration",
+		"OperationDuration",
 	}
 
 	for _, fn := range funcs {
@@ -18,3 +27,91 @@ func TestNewTimeExprs(t *testing.T) {
 		assert.True(t, hasFunc)
 	}
 }
+
+func TestParseLayout(t *testing.T) {
+	res := parseLayout("DateOnly", "2021-11-17")
+	assert.Equal(t, time.Date(2021, 11, 17, 0, 0, 0, 0, time.UTC), res)
+
+	res = parseLayout("02 Jan 2006", "17 Nov 2021")
+	assert.Equal(t, time.Date(2021, 11, 17, 0, 0, 0, 0, time.UTC), res)
+
+	assert.Panics(t, func() {
+		parseLayout("DateOnly", "17 Nov 2021")
+	})
+}
+
+func TestFormat(t *testing.T) {
+	ts := time.Date(2021, 11, 17, 16, 56, 11, 0, time.UTC)
+
+	assert.Equal(t, "2021-11-17T16:56:11Z", format(ts, "RFC3339"))
+	assert.Equal(t, "4:56PM", format(&ts, "Kitchen"))
+	assert.Equal(t, "17/11/2021", format("2021-11-17T16:56:11Z", "02/01/2006"))
+
+	assert.Panics(t, func() {
+		format("yesterday", "RFC3339")
+	})
+	assert.Panics(t, func() {
+		format(123, "RFC3339")
+	})
+}
+
+func TestInZone(t *testing.T) {
+	res := inZone("2021-11-17T16:56:11Z", "Europe/Berlin")
+	assert.Equal(t, "2021-11-17 17:56:11", format(res, "DateTime"))
+
+	assert.Panics(t, func() {
+		inZone("2021-11-17T16:56:11Z", "Unknown/Zone")
+	})
+}
+
+func TestSinceUntil(t *testing.T) {
+	assert.True(t, since(time.Now().Add(-time.Hour)) >= time.Hour)
+	assert.True(t, until(time.Now().Add(time.Hour)) <= time.Hour)
+	assert.True(t, until(time.Now().Add(time.Hour)) > 0)
+}
+
+func TestParseDuration(t *testing.T) {
+	assert.Equal(t, 90*time.Minute, parseDuration("1h30m"))
+	assert.Panics(t, func() {
+		parseDuration("1 hour")
+	})
+}
+
+func TestHumanizeDuration(t *testing.T) {
+	testCases := map[string]interface{}{
+		"0s":    time.Duration(0),
+		"45s":   45 * time.Second,
+		"3m":    3*time.Minute + 200*time.Millisecond,
+		"5m10s": 5*time.Minute + 10*time.Second,
+		"1h":    time.Hour + 5*time.Second,
+		"2d3h":  51*time.Hour + 30*time.Minute,
+		"-1m1s": -61 * time.Second,
+		"1h30m": "1h30m",
+	}
+	for expected, d := range testCases {
+		assert.Equal(t, expected, humanizeDuration(d))
+	}
+
+	assert.Panics(t, func() {
+		humanizeDuration(60)
+	})
+}
+
+func TestOperationDuration(t *testing.T) {
+	app := map[string]interface{}{
+		"status": map[string]interface{}{
+			"operationState": map[string]interface{}{
+				"startedAt":  "2021-11-17T16:56:11Z",
+				"finishedAt": "2021-11-17T16:58:41Z",
+			},
+		},
+	}
+	assert.Equal(t, 150*time.Second, operationDuration(app))
+
+	delete(app["status"].(map[string]interface{})["operationState"].(map[string]interface{}), "finishedAt")
+	assert.True(t, operationDuration(app) > 150*time.Second)
+
+	assert.Panics(t, func() {
+		operationDuration(map[string]interface{}{"metadata": map[string]interface{}{"name": "guestbook"}})
+	})
+}
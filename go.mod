@@ -3,6 +3,7 @@ module github.com/argoproj-labs/argocd-notifications
 go 1.16
 
 require (
+	github.com/antonmedv/expr v1.8.9
 	github.com/argoproj/argo-cd/v2 v2.1.7
 	github.com/argoproj/notifications-engine v0.3.1-0.20211117165611-0e1f1eda5f52
 	github.com/evanphx/json-patch v4.11.0+incompatible
@@ -10,14 +11,24 @@ require (
 	github.com/go-redis/cache/v8 v8.11.3 // indirect
 	github.com/golang/mock v1.5.0
 	github.com/grpc-ecosystem/go-grpc-middleware v1.2.0 // indirect
+	github.com/hashicorp/golang-lru v0.5.4
 	github.com/olekukonko/tablewriter v0.0.4
 	github.com/prometheus/client_golang v1.11.0
-	github.com/robfig/cron v1.2.0 // indirect
+	github.com/robfig/cron v1.2.0
 	github.com/sirupsen/logrus v1.8.1
 	github.com/slack-go/slack v0.6.6
 	github.com/spf13/cobra v1.1.3
+	github.com/spf13/pflag v1.0.5
 	github.com/stretchr/testify v1.7.0
 	github.com/whilp/git-urls v0.0.0-20191001220047-6db9661140c0
+	go.opentelemetry.io/otel v1.1.0
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc v1.1.0
+	go.opentelemetry.io/otel/exporters/stdout/stdouttrace v1.1.0
+	go.opentelemetry.io/otel/sdk v1.1.0
+	go.opentelemetry.io/otel/trace v1.1.0
+	golang.org/x/sync v0.0.0-20210220032951-036812b2e83c
+	golang.org/x/time v0.0.0-20210220033141-f8bda1e9f3ba
+	google.golang.org/grpc v1.41.0
 	k8s.io/api v0.21.0
 	k8s.io/apimachinery v0.21.0
 	k8s.io/client-go v11.0.1-0.20190816222228-6d55c1b1f1ca+incompatible
@@ -0,0 +1,156 @@
+package argocd
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/argoproj-labs/argocd-notifications/expr/shared"
+	"github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	lru "github.com/hashicorp/golang-lru"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	cacheResultHit       = "hit"
+	cacheResultMiss      = "miss"
+	cacheResultCoalesced = "coalesced"
+)
+
+var (
+	commitSHARegexp = regexp.MustCompile(`^[0-9a-f]{40}$`)
+)
+
+// cachingService caches responses of the decorated service for the given time and coalesces concurrent identical
+// calls so that rendering the same notification for many destinations results in a single repo server request
+type cachingService struct {
+	Service
+	ttl   time.Duration
+	cache *lru.Cache
+	group singleflight.Group
+	now   func() time.Time
+	// waiting is called once the call waits for the load, it lets tests wait until concurrent calls are coalesced
+	waiting func()
+	// lock serializes updates of the cache entries which are not atomic because of the expiration check
+	lock sync.Mutex
+}
+
+type cacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// NewCachingService returns the service which caches successful responses of the given service for the given time
+// keeping at most size responses. Errors are not cached.
+func NewCachingService(svc Service, ttl time.Duration, size int) (*cachingService, error) {
+	cache, err := lru.New(size)
+	if err != nil {
+		return nil, err
+	}
+	return &cachingService{Service: svc, ttl: ttl, cache: cache, now: time.Now, waiting: func() {}}, nil
+}
+
+func (svc *cachingService) GetCommitMetadata(ctx context.Context, repoURL string, commitSHA string) (*shared.CommitMetadata, error) {
+	res, err := svc.get(ctx, "GetCommitMetadata", repoURL+"@"+commitSHA, isCommitSHA(commitSHA), func(ctx context.Context) (interface{}, error) {
+		return svc.Service.GetCommitMetadata(ctx, repoURL, commitSHA)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*shared.CommitMetadata), nil
+}
+
+func (svc *cachingService) GetAppDetails(ctx context.Context, appSource *v1alpha1.ApplicationSource) (*shared.AppDetail, error) {
+	// the source includes the repo URL, the revision and the parameters which affect the details
+	key, err := json.Marshal(appSource)
+	if err != nil {
+		return nil, err
+	}
+	// details of symbolic revisions, such as branches, change over time so such details are not cached
+	res, err := svc.get(ctx, "GetAppDetails", string(key), isCommitSHA(appSource.TargetRevision), func(ctx context.Context) (interface{}, error) {
+		return svc.Service.GetAppDetails(ctx, appSource)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*shared.AppDetail), nil
+}
+
+func isCommitSHA(revision string) bool {
+	return commitSHARegexp.MatchString(revision)
+}
+
+// get returns the cached response of the method or loads it using the given function. Concurrent calls with the same
+// key wait for the single load, which is not canceled if the context of the call that started the load is done.
+// The response is cached only if it is cacheable.
+func (svc *cachingService) get(ctx context.Context, method string, key string, cacheable bool, load func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	key = method + "/" + key
+	if value, ok := svc.getCached(key); cacheable && ok {
+		argocdCacheRequests.WithLabelValues(method, cacheResultHit).Inc()
+		return value, nil
+	}
+	// loaded is set if this call started the load, the others share its result
+	loaded := false
+	ch := svc.group.DoChan(key, func() (interface{}, error) {
+		loaded = true
+		value, err := load(detachedContext{parent: ctx})
+		if err == nil && cacheable && svc.ttl > 0 {
+			svc.lock.Lock()
+			svc.cache.Add(key, cacheEntry{value: value, expires: svc.now().Add(svc.ttl)})
+			svc.lock.Unlock()
+		}
+		return value, err
+	})
+	svc.waiting()
+	select {
+	case res := <-ch:
+		// the result is received after the load is done, so the flag set by the load is visible here
+		if loaded {
+			argocdCacheRequests.WithLabelValues(method, cacheResultMiss).Inc()
+		} else {
+			argocdCacheRequests.WithLabelValues(method, cacheResultCoalesced).Inc()
+		}
+		return res.Val, res.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (svc *cachingService) getCached(key string) (interface{}, bool) {
+	svc.lock.Lock()
+	defer svc.lock.Unlock()
+	val, ok := svc.cache.Get(key)
+	if !ok {
+		return nil, false
+	}
+	entry := val.(cacheEntry)
+	if !svc.now().Before(entry.expires) {
+		svc.cache.Remove(key)
+		return nil, false
+	}
+	return entry.value, true
+}
+
+// detachedContext keeps values of the parent context but is never canceled, so that the load shared by concurrent
+// calls is not canceled together with the call which started it
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
@@ -0,0 +1,177 @@
+package argocd
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/argoproj-labs/argocd-notifications/expr/shared"
+	"github.com/argoproj-labs/argocd-notifications/shared/argocd/mocks"
+)
+
+const (
+	testCommitSHA = "0e1f1eda5f52b9a3ad2cc5c01a4e0a6d94dbb6e4"
+)
+
+func TestCachingService_GetCommitMetadata(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockService(ctrl)
+	meta := &shared.CommitMetadata{Message: "hello"}
+	mockService.EXPECT().GetCommitMetadata(gomock.Any(), "https://github.com/argoproj/argo-cd", testCommitSHA).Return(meta, nil).Times(2)
+	svc, err := NewCachingService(mockService, time.Minute, 10)
+	if !assert.NoError(t, err) {
+		return
+	}
+	now := time.Now()
+	svc.now = func() time.Time {
+		return now
+	}
+	hits := testutil.ToFloat64(argocdCacheRequests.WithLabelValues("GetCommitMetadata", cacheResultHit))
+
+	for i := 0; i < 3; i++ {
+		res, err := svc.GetCommitMetadata(context.Background(), "https://github.com/argoproj/argo-cd", testCommitSHA)
+		assert.NoError(t, err)
+		assert.Equal(t, meta, res)
+	}
+	assert.Equal(t, hits+2, testutil.ToFloat64(argocdCacheRequests.WithLabelValues("GetCommitMetadata", cacheResultHit)))
+
+	now = now.Add(time.Minute)
+	_, err = svc.GetCommitMetadata(context.Background(), "https://github.com/argoproj/argo-cd", testCommitSHA)
+	assert.NoError(t, err)
+}
+
+func TestCachingService_ErrorsAreNotCached(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockService(ctrl)
+	source := &v1alpha1.ApplicationSource{RepoURL: "https://github.com/argoproj/argo-cd", Path: "manifests", TargetRevision: testCommitSHA}
+	details := &shared.AppDetail{Type: "Kustomize"}
+	gomock.InOrder(
+		mockService.EXPECT().GetAppDetails(gomock.Any(), source).Return(nil, errors.New("unavailable")),
+		mockService.EXPECT().GetAppDetails(gomock.Any(), source).Return(details, nil),
+	)
+	svc, err := NewCachingService(mockService, time.Minute, 10)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = svc.GetAppDetails(context.Background(), source)
+	assert.Error(t, err)
+	res, err := svc.GetAppDetails(context.Background(), source)
+	assert.NoError(t, err)
+	assert.Equal(t, details, res)
+	res, err = svc.GetAppDetails(context.Background(), source)
+	assert.NoError(t, err)
+	assert.Equal(t, details, res)
+}
+
+func TestCachingService_CoalescesConcurrentCalls(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockService(ctrl)
+	release := make(chan struct{})
+	meta := &shared.CommitMetadata{Message: "hello"}
+	mockService.EXPECT().GetCommitMetadata(gomock.Any(), "https://github.com/argoproj/argo-cd", testCommitSHA).DoAndReturn(
+		func(ctx context.Context, repoURL string, commitSHA string) (*shared.CommitMetadata, error) {
+			<-release
+			return meta, nil
+		}).Times(1)
+	svc, err := NewCachingService(mockService, 0, 10)
+	if !assert.NoError(t, err) {
+		return
+	}
+	miss := testutil.ToFloat64(argocdCacheRequests.WithLabelValues("GetCommitMetadata", cacheResultMiss))
+	coalesced := testutil.ToFloat64(argocdCacheRequests.WithLabelValues("GetCommitMetadata", cacheResultCoalesced))
+	// the load is blocked until all calls wait for it, so the calls are coalesced
+	var waiting sync.WaitGroup
+	waiting.Add(3)
+	svc.waiting = waiting.Done
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := svc.GetCommitMetadata(context.Background(), "https://github.com/argoproj/argo-cd", testCommitSHA)
+			assert.NoError(t, err)
+			assert.Equal(t, meta, res)
+		}()
+	}
+	waiting.Wait()
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, miss+1, testutil.ToFloat64(argocdCacheRequests.WithLabelValues("GetCommitMetadata", cacheResultMiss)))
+	assert.Equal(t, coalesced+2, testutil.ToFloat64(argocdCacheRequests.WithLabelValues("GetCommitMetadata", cacheResultCoalesced)))
+}
+
+func TestCachingService_SymbolicRevisionsAreNotCached(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockService(ctrl)
+	source := &v1alpha1.ApplicationSource{RepoURL: "https://github.com/argoproj/argo-cd", Path: "manifests", TargetRevision: "HEAD"}
+	details := &shared.AppDetail{Type: "Kustomize"}
+	mockService.EXPECT().GetAppDetails(gomock.Any(), source).Return(details, nil).Times(2)
+	svc, err := NewCachingService(mockService, time.Minute, 10)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	for i := 0; i < 2; i++ {
+		res, err := svc.GetAppDetails(context.Background(), source)
+		assert.NoError(t, err)
+		assert.Equal(t, details, res)
+	}
+}
+
+func TestCachingService_CanceledCallDoesNotCancelLoad(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockService(ctrl)
+	release := make(chan struct{})
+	meta := &shared.CommitMetadata{Message: "hello"}
+	mockService.EXPECT().GetCommitMetadata(gomock.Any(), "https://github.com/argoproj/argo-cd", testCommitSHA).DoAndReturn(
+		func(ctx context.Context, repoURL string, commitSHA string) (*shared.CommitMetadata, error) {
+			select {
+			case <-release:
+				return meta, nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}).Times(1)
+	svc, err := NewCachingService(mockService, time.Minute, 10)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled := make(chan error)
+	go func() {
+		_, err := svc.GetCommitMetadata(ctx, "https://github.com/argoproj/argo-cd", testCommitSHA)
+		canceled <- err
+	}()
+	// give the first call time to start the load
+	time.Sleep(100 * time.Millisecond)
+	loaded := make(chan error)
+	go func() {
+		_, err := svc.GetCommitMetadata(context.Background(), "https://github.com/argoproj/argo-cd", testCommitSHA)
+		loaded <- err
+	}()
+	cancel()
+	assert.Equal(t, context.Canceled, <-canceled)
+	close(release)
+	assert.NoError(t, <-loaded)
+}
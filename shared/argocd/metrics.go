@@ -0,0 +1,34 @@
+package argocd
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	repoServerRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "argocd_notifications_repo_server_request_duration_seconds",
+			Help:    "Duration of repo server requests.",
+			Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		},
+		[]string{"method"},
+	)
+	repoServerRequestErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "argocd_notifications_repo_server_request_errors_total",
+			Help: "Number of failed repo server requests.",
+		},
+		[]string{"method", "code"},
+	)
+	argocdCacheRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "argocd_notifications_argocd_cache_requests_total",
+			Help: "Number of Argo CD lookups served by the cache (hit), the repo server (miss) or the concurrent identical lookup (coalesced).",
+		},
+		[]string{"method", "result"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(repoServerRequestDuration, repoServerRequestErrors, argocdCacheRequests)
+}
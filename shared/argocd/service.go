@@ -3,8 +3,11 @@ package argocd
 import (
 	"context"
 	"fmt"
+	"io"
+	"time"
 
 	"github.com/argoproj-labs/argocd-notifications/expr/shared"
+	"github.com/argoproj-labs/argocd-notifications/shared/tracing"
 	"github.com/argoproj/argo-cd/v2/common"
 	"github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
 	"github.com/argoproj/argo-cd/v2/reposerver/apiclient"
@@ -13,9 +16,20 @@ import (
 	"github.com/argoproj/argo-cd/v2/util/settings"
 	"github.com/argoproj/argo-cd/v2/util/tls"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/status"
 	"k8s.io/client-go/kubernetes"
 )
 
+const (
+	repoServerServiceName = "repository.RepoServerService"
+)
+
+var tracer = otel.Tracer("github.com/argoproj-labs/argocd-notifications/shared/argocd")
+
 //go:generate mockgen -destination=./mocks/service.go -package=mocks github.com/argoproj-labs/argocd-notifications/shared/argocd Service
 
 type Service interface {
@@ -54,27 +68,34 @@ func NewArgoCDService(clientset kubernetes.Interface, namespace string, repoServ
 			log.Warnf("Failed to close repo server connection: %v", err)
 		}
 	}
-	return &argoCDService{settingsMgr: settingsMgr, namespace: namespace, repoServerClient: repoClient, dispose: dispose}, nil
+	return &argoCDService{
+		db:               db.NewDB(namespace, settingsMgr, clientset),
+		settingsMgr:      settingsMgr,
+		repoServerClient: repoClient,
+		repoServerConn:   closer,
+		dispose:          dispose,
+	}, nil
 }
 
 type argoCDService struct {
-	clientset        kubernetes.Interface
-	namespace        string
+	db               db.ArgoDB
 	settingsMgr      *settings.SettingsManager
 	repoServerClient apiclient.RepoServerServiceClient
+	repoServerConn   io.Closer
 	dispose          func()
 }
 
 func (svc *argoCDService) GetCommitMetadata(ctx context.Context, repoURL string, commitSHA string) (*shared.CommitMetadata, error) {
-	argocdDB := db.NewDB(svc.namespace, svc.settingsMgr, svc.clientset)
-	repo, err := argocdDB.GetRepository(ctx, repoURL)
+	repo, err := svc.db.GetRepository(ctx, repoURL)
 	if err != nil {
 		return nil, err
 	}
+	ctx, done := startRepoServerCall(ctx, "GetRevisionMetadata")
 	metadata, err := svc.repoServerClient.GetRevisionMetadata(ctx, &apiclient.RepoServerRevisionMetadataRequest{
 		Repo:     repo,
 		Revision: commitSHA,
 	})
+	done(err)
 	if err != nil {
 		return nil, err
 	}
@@ -95,12 +116,11 @@ func (svc *argoCDService) getKustomizeOptions(source *v1alpha1.ApplicationSource
 }
 
 func (svc *argoCDService) GetAppDetails(ctx context.Context, appSource *v1alpha1.ApplicationSource) (*shared.AppDetail, error) {
-	argocdDB := db.NewDB(svc.namespace, svc.settingsMgr, svc.clientset)
-	repo, err := argocdDB.GetRepository(ctx, appSource.RepoURL)
+	repo, err := svc.db.GetRepository(ctx, appSource.RepoURL)
 	if err != nil {
 		return nil, err
 	}
-	helmRepos, err := argocdDB.ListHelmRepositories(ctx)
+	helmRepos, err := svc.db.ListHelmRepositories(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -108,12 +128,14 @@ func (svc *argoCDService) GetAppDetails(ctx context.Context, appSource *v1alpha1
 	if err != nil {
 		return nil, err
 	}
+	ctx, done := startRepoServerCall(ctx, "GetAppDetails")
 	appDetail, err := svc.repoServerClient.GetAppDetails(ctx, &apiclient.RepoServerAppDetailsQuery{
 		Repo:             repo,
 		Source:           appSource,
 		Repos:            helmRepos,
 		KustomizeOptions: kustomizeOptions,
 	})
+	done(err)
 	if err != nil {
 		return nil, err
 	}
@@ -136,6 +158,38 @@ func (svc *argoCDService) GetAppDetails(ctx context.Context, appSource *v1alpha1
 	}, nil
 }
 
+// startRepoServerCall starts the client span of the repo server gRPC call and returns the function which ends the
+// span and records the call metrics
+func startRepoServerCall(ctx context.Context, method string) (context.Context, func(err error)) {
+	ctx, span := tracer.Start(ctx, repoServerServiceName+"/"+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.RPCSystemKey.String("grpc"),
+			semconv.RPCServiceKey.String(repoServerServiceName),
+			semconv.RPCMethodKey.String(method)))
+	start := time.Now()
+	return ctx, func(err error) {
+		repoServerRequestDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+		if err != nil {
+			repoServerRequestErrors.WithLabelValues(method, status.Code(err).String()).Inc()
+		}
+		tracing.End(span, err)
+	}
+}
+
+// CheckConnection returns an error if the repo server connection is not usable
+func (svc *argoCDService) CheckConnection() error {
+	conn, ok := svc.repoServerConn.(interface{ GetState() connectivity.State })
+	if !ok {
+		return nil
+	}
+	switch state := conn.GetState(); state {
+	case connectivity.TransientFailure, connectivity.Shutdown:
+		return fmt.Errorf("repo server connection is %s", state)
+	}
+	return nil
+}
+
 func (svc *argoCDService) Close() {
 	svc.dispose()
 }
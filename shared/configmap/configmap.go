@@ -0,0 +1,86 @@
+package configmap
+
+import (
+	"context"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// MaxDataSize keeps the data of ConfigMaps used as stores below the ConfigMap size limit
+const MaxDataSize = 900 * 1024
+
+// Get returns the ConfigMap with the given name or nil if the ConfigMap does not exist
+func Get(ctx context.Context, clientset kubernetes.Interface, namespace string, name string) (*v1.ConfigMap, error) {
+	cm, err := clientset.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierr.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return cm, nil
+}
+
+// Update applies the modification to the latest version of the ConfigMap and retries on conflicts, so that the
+// ConfigMap can be updated concurrently. The ConfigMap is created if it does not exist, unless the modified ConfigMap
+// has no data. Returns the updated ConfigMap or nil if the ConfigMap has not been created.
+func Update(ctx context.Context, clientset kubernetes.Interface, namespace string, name string, modify func(cm *v1.ConfigMap) error) (*v1.ConfigMap, error) {
+	configMaps := clientset.CoreV1().ConfigMaps(namespace)
+	var updated *v1.ConfigMap
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := configMaps.Get(ctx, name, metav1.GetOptions{})
+		if apierr.IsNotFound(err) {
+			cm = &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}, Data: map[string]string{}}
+			if err := modify(cm); err != nil {
+				return err
+			}
+			if len(cm.Data) == 0 {
+				updated = nil
+				return nil
+			}
+			updated, err = configMaps.Create(ctx, cm, metav1.CreateOptions{})
+			if apierr.IsAlreadyExists(err) {
+				// retry as a conflict so that the ConfigMap created concurrently is modified
+				return apierr.NewConflict(v1.Resource("configmaps"), name, err)
+			}
+			return err
+		} else if err != nil {
+			return err
+		}
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		if err := modify(cm); err != nil {
+			return err
+		}
+		updated, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// DataSize returns the size of the ConfigMap data
+func DataSize(data map[string]string) int {
+	size := 0
+	for k, v := range data {
+		size += len(k) + len(v)
+	}
+	return size
+}
+
+// SortedKeys returns the keys of the ConfigMap data in the alphabetical order
+func SortedKeys(data map[string]string) []string {
+	var keys []string
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,74 @@
+package configmap
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestUpdate(t *testing.T) {
+	ctx := context.TODO()
+	clientset := fake.NewSimpleClientset()
+
+	cm, err := Get(ctx, clientset, "default", "my-config-map")
+	assert.NoError(t, err)
+	assert.Nil(t, cm)
+
+	cm, err = Update(ctx, clientset, "default", "my-config-map", func(cm *v1.ConfigMap) error {
+		cm.Data["foo"] = "1"
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"foo": "1"}, cm.Data)
+
+	_, err = Update(ctx, clientset, "default", "my-config-map", func(cm *v1.ConfigMap) error {
+		cm.Data["bar"] = "2"
+		return nil
+	})
+	assert.NoError(t, err)
+
+	cm, err = Get(ctx, clientset, "default", "my-config-map")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"foo": "1", "bar": "2"}, cm.Data)
+}
+
+func TestUpdate_DoesNotCreateEmptyConfigMap(t *testing.T) {
+	ctx := context.TODO()
+	clientset := fake.NewSimpleClientset()
+
+	cm, err := Update(ctx, clientset, "default", "my-config-map", func(cm *v1.ConfigMap) error {
+		delete(cm.Data, "foo")
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Nil(t, cm)
+
+	cm, err = Get(ctx, clientset, "default", "my-config-map")
+	assert.NoError(t, err)
+	assert.Nil(t, cm)
+}
+
+func TestUpdate_ModificationFailed(t *testing.T) {
+	ctx := context.TODO()
+	clientset := fake.NewSimpleClientset()
+
+	_, err := Update(ctx, clientset, "default", "my-config-map", func(cm *v1.ConfigMap) error {
+		cm.Data["foo"] = "1"
+		return errors.New("fail")
+	})
+	assert.EqualError(t, err, "fail")
+
+	cm, err := Get(ctx, clientset, "default", "my-config-map")
+	assert.NoError(t, err)
+	assert.Nil(t, cm)
+}
+
+func TestDataSizeAndSortedKeys(t *testing.T) {
+	data := map[string]string{"b": "22", "a": "1"}
+	assert.Equal(t, 5, DataSize(data))
+	assert.Equal(t, []string{"a", "b"}, SortedKeys(data))
+}
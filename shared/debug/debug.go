@@ -0,0 +1,226 @@
+package debug
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/argoproj-labs/argocd-notifications/shared/k8s"
+
+	"github.com/ghodss/yaml"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	redacted = "******"
+	// serviceKeyPrefix is the prefix of notification service configs in the notifications ConfigMap
+	serviceKeyPrefix = "service."
+)
+
+var (
+	sensitiveKey = regexp.MustCompile(`(?i)(token|password|secret|apikey|api_key|credential|private|auth|signing|headers)`)
+)
+
+// State returns the runtime state dumped by the debug server. The request might be used to customize the state.
+type State func(r *http.Request) (interface{}, error)
+
+// NewServeMux returns the mux which serves net/http/pprof profiles under /debug/pprof/ and the runtime state
+// under /debug/state
+func NewServeMux(state State) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/state", func(w http.ResponseWriter, r *http.Request) {
+		res, err := state(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(res)
+	})
+	return mux
+}
+
+// Serve starts the debug server in background if the port is positive
+func Serve(port int, state State) {
+	if port <= 0 {
+		return
+	}
+	go func() {
+		log.Fatal(http.ListenAndServe(fmt.Sprintf("0.0.0.0:%d", port), NewServeMux(state)))
+	}()
+	log.Infof("serving debug endpoints on port %d", port)
+}
+
+// NewState returns the state which includes sizes of the informers caches, the command flags and the notifications
+// configuration. Informers of the notifications ConfigMap and Secret are expected under the configmaps and secrets
+// names.
+func NewState(flags *pflag.FlagSet, namespace string, getInformers func() map[string]cache.SharedIndexInformer) State {
+	return func(r *http.Request) (interface{}, error) {
+		informers := getInformers()
+		config, err := GetNotificationsConfig(
+			informers["configmaps"], fmt.Sprintf("%s/%s", namespace, k8s.ConfigMapName),
+			informers["secrets"], fmt.Sprintf("%s/%s", namespace, k8s.SecretName))
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"informers": GetInformerStats(r, informers),
+			"flags":     GetFlags(flags),
+			"config":    config,
+		}, nil
+	}
+}
+
+// InformerStats describes the informer cache
+type InformerStats struct {
+	Objects int `json:"objects"`
+	// ApproximateBytes is the total size of cached objects serialized to JSON
+	ApproximateBytes int `json:"approximateBytes,omitempty"`
+}
+
+// GetInformerStats returns sizes of the informers caches. Objects are serialized to estimate cache sizes in bytes only
+// if the sizes query parameter of the request is true since it is expensive with many cached objects.
+func GetInformerStats(r *http.Request, informers map[string]cache.SharedIndexInformer) map[string]InformerStats {
+	withSizes, _ := strconv.ParseBool(r.URL.Query().Get("sizes"))
+	res := map[string]InformerStats{}
+	for name, informer := range informers {
+		stats := InformerStats{}
+		if withSizes {
+			for _, obj := range informer.GetStore().List() {
+				if data, err := json.Marshal(obj); err == nil {
+					stats.ApproximateBytes += len(data)
+				}
+				stats.Objects++
+			}
+		} else {
+			stats.Objects = len(informer.GetStore().ListKeys())
+		}
+		res[name] = stats
+	}
+	return res
+}
+
+// GetFlags returns values of the command flags with values of sensitive flags redacted
+func GetFlags(flags *pflag.FlagSet) map[string]string {
+	res := map[string]string{}
+	flags.VisitAll(func(flag *pflag.Flag) {
+		val := flag.Value.String()
+		if sensitiveKey.MatchString(flag.Name) && val != "" && val != "[]" {
+			val = redacted
+		}
+		res[flag.Name] = val
+	})
+	return res
+}
+
+// NotificationsConfig is the notifications configuration with sensitive values redacted
+type NotificationsConfig struct {
+	ConfigMap map[string]interface{} `json:"configMap"`
+	// SecretKeys holds keys of the notifications Secret without values
+	SecretKeys []string `json:"secretKeys"`
+}
+
+// GetNotificationsConfig returns the notifications configuration from the ConfigMap and Secret informers
+func GetNotificationsConfig(configMapInformer cache.SharedIndexInformer, configMapKey string, secretInformer cache.SharedIndexInformer, secretKey string) (*NotificationsConfig, error) {
+	res := &NotificationsConfig{ConfigMap: map[string]interface{}{}, SecretKeys: []string{}}
+	obj, exists, err := configMapInformer.GetStore().GetByKey(configMapKey)
+	if err != nil {
+		return nil, err
+	}
+	if configMap, ok := obj.(*v1.ConfigMap); exists && ok {
+		for k, v := range configMap.Data {
+			res.ConfigMap[k] = redactYAML(k, v)
+		}
+	}
+	obj, exists, err = secretInformer.GetStore().GetByKey(secretKey)
+	if err != nil {
+		return nil, err
+	}
+	if secret, ok := obj.(*v1.Secret); exists && ok {
+		for k := range secret.Data {
+			res.SecretKeys = append(res.SecretKeys, k)
+		}
+		sort.Strings(res.SecretKeys)
+	}
+	return res, nil
+}
+
+// redactYAML returns the parsed YAML value with sensitive fields redacted or the redacted string if the value is
+// not structured. Paths and query parameters of URLs in service configs are redacted since webhook URLs often
+// embed tokens.
+func redactYAML(key string, val string) interface{} {
+	if sensitiveKey.MatchString(key) {
+		return redacted
+	}
+	redactURLPaths := strings.HasPrefix(key, serviceKeyPrefix)
+	var parsed interface{}
+	if err := yaml.Unmarshal([]byte(val), &parsed); err != nil {
+		return redactString(val, redactURLPaths)
+	}
+	switch parsed.(type) {
+	case map[string]interface{}, []interface{}:
+		return redact(parsed, redactURLPaths)
+	}
+	return redactString(val, redactURLPaths)
+}
+
+func redact(val interface{}, redactURLPaths bool) interface{} {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		res := map[string]interface{}{}
+		for k, item := range v {
+			if sensitiveKey.MatchString(k) {
+				res[k] = redacted
+			} else {
+				res[k] = redact(item, redactURLPaths)
+			}
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(v))
+		for i := range v {
+			res[i] = redact(v[i], redactURLPaths)
+		}
+		return res
+	case string:
+		return redactString(v, redactURLPaths)
+	}
+	return val
+}
+
+// redactString removes credentials from URLs and optionally paths, query parameters and fragments
+func redactString(val string, redactURLPaths bool) string {
+	parsed, err := url.Parse(val)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return val
+	}
+	changed := false
+	if parsed.User != nil {
+		parsed.User = url.User("redacted")
+		changed = true
+	}
+	if redactURLPaths && (strings.Trim(parsed.Path, "/") != "" || parsed.RawQuery != "" || parsed.Fragment != "") {
+		parsed.Path, parsed.RawPath, parsed.RawQuery, parsed.Fragment = "/redacted", "", "", ""
+		changed = true
+	}
+	if !changed {
+		return val
+	}
+	return parsed.String()
+}
@@ -0,0 +1,42 @@
+package healthz
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Checker returns an error if the checked component is not ready
+type Checker func() error
+
+// LivenessHandler reports that the process is up and able to serve requests
+func LivenessHandler(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok"))
+}
+
+// NewReadinessHandler returns a handler that runs the given checks and responds with 503 if any of them fails
+func NewReadinessHandler(checks map[string]Checker) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		var names []string
+		for name := range checks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var failures []string
+		for _, name := range names {
+			if err := checks[name](); err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", name, err))
+			}
+		}
+		if len(failures) > 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(strings.Join(failures, "\n")))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	}
+}
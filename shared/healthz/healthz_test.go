@@ -0,0 +1,33 @@
+package healthz
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadinessHandler_Ready(t *testing.T) {
+	handler := NewReadinessHandler(map[string]Checker{
+		"config": func() error { return nil },
+	})
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ok", w.Body.String())
+}
+
+func TestReadinessHandler_NotReady(t *testing.T) {
+	handler := NewReadinessHandler(map[string]Checker{
+		"config": func() error { return nil },
+		"caches": func() error { return errors.New("not synced") },
+	})
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "caches: not synced", w.Body.String())
+}
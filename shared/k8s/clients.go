@@ -6,8 +6,9 @@ import (
 )
 
 var (
-	Applications = schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "applications"}
-	AppProjects  = schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "appprojects"}
+	Applications    = schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "applications"}
+	AppProjects     = schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "appprojects"}
+	ApplicationSets = schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "applicationsets"}
 )
 
 func NewAppClient(client dynamic.Interface, namespace string) dynamic.ResourceInterface {
@@ -19,3 +20,8 @@ func NewAppProjClient(client dynamic.Interface, namespace string) dynamic.Resour
 	resClient := client.Resource(AppProjects).Namespace(namespace)
 	return resClient
 }
+
+func NewAppSetClient(client dynamic.Interface, namespace string) dynamic.ResourceInterface {
+	resClient := client.Resource(ApplicationSets).Namespace(namespace)
+	return resClient
+}
@@ -0,0 +1,115 @@
+package settings
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/argoproj/notifications-engine/pkg/services"
+	"github.com/argoproj/notifications-engine/pkg/subscriptions"
+	"github.com/ghodss/yaml"
+	"github.com/robfig/cron"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/argoproj-labs/argocd-notifications/shared/configmap"
+)
+
+const (
+	digestKeyPrefix = "digest."
+	// DigestAnnotationPrefix is the prefix of annotations which route subscriptions to digests, e.g.
+	// notifications.argoproj.io/digest.on-deployed.slack: deployments or notifications.argoproj.io/digest.slack: deployments
+	DigestAnnotationPrefix = subscriptions.AnnotationPrefix + "/digest."
+)
+
+// Digest batches notifications into periodic summaries. Notifications are added to the digest if the destination is
+// listed in the digest destinations or if the subscription refers to the digest using the digest annotation.
+type Digest struct {
+	Name         string                 `json:"-"`
+	Destinations []services.Destination `json:"destinations,omitempty"`
+	// Interval between digests. Mutually exclusive with Schedule.
+	Interval metav1.Duration `json:"interval,omitempty"`
+	// Schedule is the cron schedule of digests. Mutually exclusive with Interval.
+	Schedule string `json:"schedule,omitempty"`
+	// Template is the name of the template which renders the digest
+	Template string `json:"template"`
+}
+
+func (d Digest) validate() error {
+	if d.Template == "" {
+		return fmt.Errorf("template is required")
+	}
+	if (d.Interval.Duration > 0) == (d.Schedule != "") {
+		return fmt.Errorf("either interval or schedule is required")
+	}
+	if d.Interval.Duration < 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+	if d.Schedule != "" {
+		if _, err := cron.ParseStandard(d.Schedule); err != nil {
+			return fmt.Errorf("invalid schedule: %v", err)
+		}
+	}
+	return nil
+}
+
+// Digests holds digests configured in the notifications ConfigMap
+type Digests []Digest
+
+// Get returns the digest of the destination or nil if notifications are sent to the destination right away
+func (d Digests) Get(dest services.Destination) *Digest {
+	for i := range d {
+		for _, digestDest := range d[i].Destinations {
+			if digestDest == dest {
+				return &d[i]
+			}
+		}
+	}
+	return nil
+}
+
+// GetByName returns the digest with the given name or nil if the digest is not configured
+func (d Digests) GetByName(name string) *Digest {
+	for i := range d {
+		if d[i].Name == name {
+			return &d[i]
+		}
+	}
+	return nil
+}
+
+// DigestAnnotationKey returns the key of the annotation which routes the subscription to a digest. The trigger is
+// optional: the annotation without the trigger applies to all triggers of the service.
+func DigestAnnotationKey(trigger string, service string) string {
+	if trigger == "" {
+		return DigestAnnotationPrefix + service
+	}
+	return fmt.Sprintf("%s%s.%s", DigestAnnotationPrefix, trigger, service)
+}
+
+// GetDigestName returns the name of the digest the annotations route the trigger notifications to the service to
+func GetDigestName(annotations map[string]string, trigger string, service string) string {
+	if name, ok := annotations[DigestAnnotationKey(trigger, service)]; ok {
+		return strings.TrimSpace(name)
+	}
+	return strings.TrimSpace(annotations[DigestAnnotationKey("", service)])
+}
+
+// ParseDigests returns digests configured in the "digest.<name>" keys of the notifications ConfigMap
+func ParseDigests(configMap *v1.ConfigMap) (Digests, error) {
+	var digests Digests
+	for _, k := range configmap.SortedKeys(configMap.Data) {
+		if !strings.HasPrefix(k, digestKeyPrefix) {
+			continue
+		}
+		v := configMap.Data[k]
+		digest := Digest{Name: strings.TrimPrefix(k, digestKeyPrefix)}
+		if err := yaml.Unmarshal([]byte(v), &digest); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s: %v", k, err)
+		}
+		if err := digest.validate(); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", k, err)
+		}
+		digests = append(digests, digest)
+	}
+	return digests, nil
+}
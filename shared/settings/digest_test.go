@@ -0,0 +1,82 @@
+package settings
+
+import (
+	"testing"
+	"time"
+
+	"github.com/argoproj/notifications-engine/pkg/services"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestParseDigests(t *testing.T) {
+	digests, err := ParseDigests(&v1.ConfigMap{Data: map[string]string{
+		"digest.deployments": `
+destinations:
+- service: slack
+  recipient: deployments
+interval: 15m
+template: app-digest
+`,
+		"digest.daily": `
+destinations:
+- service: email
+  recipient: team@example.com
+schedule: "0 9 * * *"
+template: app-digest
+`,
+	}})
+
+	assert.NoError(t, err)
+	if !assert.Len(t, digests, 2) {
+		return
+	}
+	assert.Equal(t, "daily", digests[0].Name)
+	assert.Equal(t, "deployments", digests[1].Name)
+	deployments := digests.Get(services.Destination{Service: "slack", Recipient: "deployments"})
+	if assert.NotNil(t, deployments) {
+		assert.Equal(t, "deployments", deployments.Name)
+		assert.Equal(t, 15*time.Minute, deployments.Interval.Duration)
+		assert.Equal(t, "app-digest", deployments.Template)
+	}
+	assert.Nil(t, digests.Get(services.Destination{Service: "slack", Recipient: "general"}))
+}
+
+func TestParseDigests_Invalid(t *testing.T) {
+	for name, digestYaml := range map[string]string{
+		"NoSchedule": `
+destinations:
+- service: slack
+  recipient: deployments
+template: app-digest
+`,
+		"InvalidSchedule": `
+destinations:
+- service: slack
+  recipient: deployments
+schedule: "every day"
+template: app-digest
+`,
+		"NoTemplate": `
+destinations:
+- service: slack
+  recipient: deployments
+interval: 15m
+`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseDigests(&v1.ConfigMap{Data: map[string]string{"digest.deployments": digestYaml}})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestGetDigestName(t *testing.T) {
+	annotations := map[string]string{
+		"notifications.argoproj.io/digest.slack":             "daily",
+		"notifications.argoproj.io/digest.on-deployed.slack": " deployments ",
+	}
+	assert.Equal(t, "deployments", GetDigestName(annotations, "on-deployed", "slack"))
+	assert.Equal(t, "daily", GetDigestName(annotations, "on-sync-failed", "slack"))
+	assert.Equal(t, "", GetDigestName(annotations, "on-deployed", "email"))
+}
@@ -0,0 +1,30 @@
+package settings
+
+import (
+	"fmt"
+
+	"github.com/argoproj-labs/argocd-notifications/expr/repo"
+	"github.com/ghodss/yaml"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	gitProvidersKey = "gitProviders"
+)
+
+// ParseGitProviders returns providers of self-hosted Git servers configured in the "gitProviders" key of the
+// notifications ConfigMap, e.g. "git.example.com: gitlab"
+func ParseGitProviders(configMap *v1.ConfigMap) (repo.GitProviders, error) {
+	providersYaml, ok := configMap.Data[gitProvidersKey]
+	if !ok {
+		return nil, nil
+	}
+	providers := repo.GitProviders{}
+	if err := yaml.Unmarshal([]byte(providersYaml), &providers); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %v", gitProvidersKey, err)
+	}
+	if err := providers.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", gitProvidersKey, err)
+	}
+	return providers, nil
+}
@@ -0,0 +1,32 @@
+package settings
+
+import (
+	"testing"
+
+	"github.com/argoproj-labs/argocd-notifications/expr/repo"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestParseGitProviders(t *testing.T) {
+	providers, err := ParseGitProviders(&v1.ConfigMap{Data: map[string]string{
+		"gitProviders": `
+git.example.com: gitlab
+bitbucket.example.com: bitbucketServer
+`,
+	}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, repo.GitProviders{
+		"git.example.com":       repo.ProviderGitLab,
+		"bitbucket.example.com": repo.ProviderBitbucketServer,
+	}, providers)
+}
+
+func TestParseGitProviders_Invalid(t *testing.T) {
+	_, err := ParseGitProviders(&v1.ConfigMap{Data: map[string]string{
+		"gitProviders": `git.example.com: svn`,
+	}})
+
+	assert.Error(t, err)
+}
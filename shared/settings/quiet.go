@@ -0,0 +1,150 @@
+package settings
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/robfig/cron"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/argoproj-labs/argocd-notifications/shared/configmap"
+)
+
+const (
+	quietWindowKeyPrefix = "quietWindow."
+)
+
+type QuietWindowAction string
+
+const (
+	// QuietWindowActionDrop drops muted notifications
+	QuietWindowActionDrop QuietWindowAction = "drop"
+	// QuietWindowActionSummary sends muted notifications as a single summary when the window ends
+	QuietWindowActionSummary QuietWindowAction = "summary"
+)
+
+// QuietWindow mutes notifications while the window is active
+type QuietWindow struct {
+	Name string `json:"-"`
+	// Schedule is the cron schedule of the window start
+	Schedule string          `json:"schedule,omitempty"`
+	Duration metav1.Duration `json:"duration,omitempty"`
+	// Timezone of the schedule. Defaults to UTC.
+	Timezone string `json:"timezone,omitempty"`
+	// ProjectSyncWindows makes the window active while a deny sync window of the application project is active
+	ProjectSyncWindows bool `json:"projectSyncWindows,omitempty"`
+	// Triggers lists muted triggers. All triggers are muted if empty.
+	Triggers []string          `json:"triggers,omitempty"`
+	Action   QuietWindowAction `json:"action,omitempty"`
+	// SummaryTemplate is the name of the template which renders the summary of muted notifications
+	SummaryTemplate string `json:"summaryTemplate,omitempty"`
+
+	location *time.Location
+	schedule cron.Schedule
+}
+
+// Mutes returns true if the window mutes notifications of the given trigger
+func (w QuietWindow) Mutes(trigger string) bool {
+	if len(w.Triggers) == 0 {
+		return true
+	}
+	for _, t := range w.Triggers {
+		if t == trigger {
+			return true
+		}
+	}
+	return false
+}
+
+// ActiveUntil returns the end of the scheduled window if the window is active at the given time
+func (w QuietWindow) ActiveUntil(now time.Time) (time.Time, bool) {
+	if w.schedule == nil {
+		return time.Time{}, false
+	}
+	return windowEnd(w.schedule, w.Duration.Duration, now.In(w.location))
+}
+
+// SyncWindowActiveUntil returns the end of the sync window with the given cron schedule, duration and time zone if
+// the window is active at the given time
+func SyncWindowActiveUntil(schedule string, duration string, timezone string, now time.Time) (time.Time, bool) {
+	location, err := loadLocation(timezone)
+	if err != nil {
+		return time.Time{}, false
+	}
+	parsedSchedule, err := cron.ParseStandard(schedule)
+	if err != nil {
+		return time.Time{}, false
+	}
+	parsedDuration, err := time.ParseDuration(duration)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return windowEnd(parsedSchedule, parsedDuration, now.In(location))
+}
+
+// windowEnd returns the end of the window which started within the duration before the given time
+func windowEnd(schedule cron.Schedule, duration time.Duration, now time.Time) (time.Time, bool) {
+	start := schedule.Next(now.Add(-duration))
+	if start.After(now) {
+		return time.Time{}, false
+	}
+	return start.Add(duration), true
+}
+
+func loadLocation(timezone string) (*time.Location, error) {
+	if timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(timezone)
+}
+
+func (w *QuietWindow) init() error {
+	if w.Action == "" {
+		w.Action = QuietWindowActionDrop
+	}
+	if w.Action != QuietWindowActionDrop && w.Action != QuietWindowActionSummary {
+		return fmt.Errorf("action must be one of: %s, %s", QuietWindowActionDrop, QuietWindowActionSummary)
+	}
+	if (w.Schedule != "") == w.ProjectSyncWindows {
+		return fmt.Errorf("either schedule or projectSyncWindows is required")
+	}
+	location, err := loadLocation(w.Timezone)
+	if err != nil {
+		return fmt.Errorf("invalid timezone: %v", err)
+	}
+	w.location = location
+	if w.Schedule != "" {
+		if w.Duration.Duration <= 0 {
+			return fmt.Errorf("duration must be positive")
+		}
+		if w.schedule, err = cron.ParseStandard(w.Schedule); err != nil {
+			return fmt.Errorf("invalid schedule: %v", err)
+		}
+	}
+	return nil
+}
+
+type QuietWindows []QuietWindow
+
+// ParseQuietWindows returns quiet windows configured in the "quietWindow.<name>" keys of the notifications ConfigMap
+func ParseQuietWindows(configMap *v1.ConfigMap) (QuietWindows, error) {
+	var windows QuietWindows
+	for _, k := range configmap.SortedKeys(configMap.Data) {
+		if !strings.HasPrefix(k, quietWindowKeyPrefix) {
+			continue
+		}
+		v := configMap.Data[k]
+		window := QuietWindow{Name: strings.TrimPrefix(k, quietWindowKeyPrefix)}
+		if err := yaml.Unmarshal([]byte(v), &window); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s: %v", k, err)
+		}
+		if err := window.init(); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", k, err)
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
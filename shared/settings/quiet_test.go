@@ -0,0 +1,92 @@
+package settings
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestParseQuietWindows(t *testing.T) {
+	windows, err := ParseQuietWindows(&v1.ConfigMap{Data: map[string]string{
+		"quietWindow.night": `
+schedule: "0 22 * * *"
+duration: 8h
+timezone: Europe/Berlin
+triggers: [on-deployed]
+action: summary
+`,
+	}})
+
+	assert.NoError(t, err)
+	if !assert.Len(t, windows, 1) {
+		return
+	}
+	night := windows[0]
+	assert.Equal(t, "night", night.Name)
+	assert.True(t, night.Mutes("on-deployed"))
+	assert.False(t, night.Mutes("on-sync-failed"))
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	assert.NoError(t, err)
+	end, active := night.ActiveUntil(time.Date(2021, 11, 17, 23, 0, 0, 0, berlin))
+	assert.True(t, active)
+	assert.True(t, time.Date(2021, 11, 18, 6, 0, 0, 0, berlin).Equal(end))
+	_, active = night.ActiveUntil(time.Date(2021, 11, 17, 20, 0, 0, 0, time.UTC))
+	assert.False(t, active)
+}
+
+func TestParseQuietWindows_SortedByName(t *testing.T) {
+	windows, err := ParseQuietWindows(&v1.ConfigMap{Data: map[string]string{
+		"quietWindow.weekend": `
+schedule: "0 0 * * 6"
+duration: 48h
+`,
+		"quietWindow.night": `
+schedule: "0 22 * * *"
+duration: 8h
+`,
+	}})
+
+	assert.NoError(t, err)
+	if assert.Len(t, windows, 2) {
+		assert.Equal(t, "night", windows[0].Name)
+		assert.Equal(t, "weekend", windows[1].Name)
+	}
+}
+
+func TestParseQuietWindows_Invalid(t *testing.T) {
+	for name, windowYaml := range map[string]string{
+		"NoSchedule": `
+duration: 8h
+`,
+		"NoDuration": `
+schedule: "0 22 * * *"
+`,
+		"InvalidTimezone": `
+schedule: "0 22 * * *"
+duration: 8h
+timezone: Mars/Olympus
+`,
+		"InvalidAction": `
+projectSyncWindows: true
+action: defer
+`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseQuietWindows(&v1.ConfigMap{Data: map[string]string{"quietWindow.night": windowYaml}})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSyncWindowActiveUntil(t *testing.T) {
+	now := time.Date(2021, 11, 17, 10, 30, 0, 0, time.UTC)
+	end, active := SyncWindowActiveUntil("0 10 * * *", "1h", "", now)
+	assert.True(t, active)
+	assert.Equal(t, time.Date(2021, 11, 17, 11, 0, 0, 0, time.UTC), end)
+
+	_, active = SyncWindowActiveUntil("0 12 * * *", "1h", "", now)
+	assert.False(t, active)
+}
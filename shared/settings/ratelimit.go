@@ -0,0 +1,149 @@
+package settings
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	rateLimitKey       = "rateLimit"
+	rateLimitKeyPrefix = rateLimitKey + "."
+
+	defaultMaxQueueDelay = 5 * time.Minute
+)
+
+type RateLimitAction string
+
+const (
+	// RateLimitActionDrop drops notifications over the limit
+	RateLimitActionDrop RateLimitAction = "drop"
+	// RateLimitActionQueue delays notifications over the limit until the limit allows to send them
+	RateLimitActionQueue RateLimitAction = "queue"
+)
+
+// Limit allows to send Limit notifications per Interval with bursts of at most Burst notifications
+type Limit struct {
+	Limit    int             `json:"limit"`
+	Interval metav1.Duration `json:"interval"`
+	// Burst defaults to Limit
+	Burst int `json:"burst,omitempty"`
+}
+
+func (l Limit) GetBurst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Limit
+}
+
+func (l Limit) validate() error {
+	if l.Limit < 1 || l.Interval.Duration <= 0 {
+		return fmt.Errorf("limit and interval must be positive")
+	}
+	if l.Burst < 0 {
+		return fmt.Errorf("burst must not be negative")
+	}
+	return nil
+}
+
+// RateLimitPolicy limits notifications sent through the service, to each recipient of the service and
+// about each application (or any other resource) to the recipients of the service
+type RateLimitPolicy struct {
+	Service       *Limit          `json:"service,omitempty"`
+	Recipient     *Limit          `json:"recipient,omitempty"`
+	Application   *Limit          `json:"application,omitempty"`
+	Action        RateLimitAction `json:"action,omitempty"`
+	MaxQueueDelay metav1.Duration `json:"maxQueueDelay,omitempty"`
+}
+
+func (p RateLimitPolicy) validate() error {
+	for _, l := range []*Limit{p.Service, p.Recipient, p.Application} {
+		if l == nil {
+			continue
+		}
+		if err := l.validate(); err != nil {
+			return err
+		}
+	}
+	if p.Action != RateLimitActionDrop && p.Action != RateLimitActionQueue {
+		return fmt.Errorf("action must be one of: %s, %s", RateLimitActionDrop, RateLimitActionQueue)
+	}
+	return nil
+}
+
+// merge returns the policy with the fields set in the other policy overridden
+func (p RateLimitPolicy) merge(other RateLimitPolicy) RateLimitPolicy {
+	if other.Service != nil {
+		p.Service = other.Service
+	}
+	if other.Recipient != nil {
+		p.Recipient = other.Recipient
+	}
+	if other.Application != nil {
+		p.Application = other.Application
+	}
+	if other.Action != "" {
+		p.Action = other.Action
+	}
+	if other.MaxQueueDelay.Duration > 0 {
+		p.MaxQueueDelay = other.MaxQueueDelay
+	}
+	return p
+}
+
+// RateLimitPolicies holds rate limit policies of notification services
+type RateLimitPolicies struct {
+	Default  *RateLimitPolicy
+	Services map[string]*RateLimitPolicy
+}
+
+// Get returns the rate limit policy of the service with the given name or nil if notifications are not limited
+func (p RateLimitPolicies) Get(service string) *RateLimitPolicy {
+	if policy, ok := p.Services[service]; ok {
+		return policy
+	}
+	return p.Default
+}
+
+// ParseRateLimitPolicies returns rate limit policies configured in the notifications ConfigMap: the "rateLimit" key
+// configures limits of all services and the "rateLimit.<service-name>" keys override limits of the specific service.
+func ParseRateLimitPolicies(configMap *v1.ConfigMap) (RateLimitPolicies, error) {
+	policies := RateLimitPolicies{Services: map[string]*RateLimitPolicy{}}
+	defaultPolicy := RateLimitPolicy{Action: RateLimitActionDrop, MaxQueueDelay: metav1.Duration{Duration: defaultMaxQueueDelay}}
+	if policyYaml, ok := configMap.Data[rateLimitKey]; ok {
+		policy, err := unmarshalRateLimitPolicy(rateLimitKey, policyYaml, defaultPolicy)
+		if err != nil {
+			return policies, err
+		}
+		policies.Default = &policy
+		defaultPolicy = policy
+	}
+	for k, v := range configMap.Data {
+		if !strings.HasPrefix(k, rateLimitKeyPrefix) {
+			continue
+		}
+		policy, err := unmarshalRateLimitPolicy(k, v, defaultPolicy)
+		if err != nil {
+			return policies, err
+		}
+		policies.Services[strings.TrimPrefix(k, rateLimitKeyPrefix)] = &policy
+	}
+	return policies, nil
+}
+
+func unmarshalRateLimitPolicy(key string, policyYaml string, defaultPolicy RateLimitPolicy) (RateLimitPolicy, error) {
+	var policy RateLimitPolicy
+	if err := yaml.Unmarshal([]byte(policyYaml), &policy); err != nil {
+		return policy, fmt.Errorf("failed to unmarshal %s: %v", key, err)
+	}
+	policy = defaultPolicy.merge(policy)
+	if err := policy.validate(); err != nil {
+		return policy, fmt.Errorf("invalid %s: %v", key, err)
+	}
+	return policy, nil
+}
@@ -0,0 +1,61 @@
+package settings
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestParseRateLimitPolicies(t *testing.T) {
+	policies, err := ParseRateLimitPolicies(&v1.ConfigMap{Data: map[string]string{
+		"rateLimit": `
+recipient:
+  limit: 10
+  interval: 1m
+`,
+		"rateLimit.slack": `
+application:
+  limit: 2
+  interval: 10m
+  burst: 1
+action: queue
+`,
+	}})
+
+	assert.NoError(t, err)
+	webhook := policies.Get("webhook")
+	if assert.NotNil(t, webhook) {
+		assert.Equal(t, RateLimitActionDrop, webhook.Action)
+		assert.Equal(t, 10, webhook.Recipient.GetBurst())
+		assert.Nil(t, webhook.Application)
+	}
+	slack := policies.Get("slack")
+	if assert.NotNil(t, slack) {
+		assert.Equal(t, RateLimitActionQueue, slack.Action)
+		assert.Equal(t, 10, slack.Recipient.Limit)
+		assert.Equal(t, 10*time.Minute, slack.Application.Interval.Duration)
+		assert.Equal(t, 1, slack.Application.GetBurst())
+		assert.Equal(t, defaultMaxQueueDelay, slack.MaxQueueDelay.Duration)
+	}
+}
+
+func TestParseRateLimitPolicies_NotConfigured(t *testing.T) {
+	policies, err := ParseRateLimitPolicies(&v1.ConfigMap{})
+
+	assert.NoError(t, err)
+	assert.Nil(t, policies.Get("slack"))
+}
+
+func TestParseRateLimitPolicies_Invalid(t *testing.T) {
+	_, err := ParseRateLimitPolicies(&v1.ConfigMap{Data: map[string]string{
+		"rateLimit.slack": `
+recipient:
+  limit: 10
+action: ignore
+`,
+	}})
+
+	assert.Error(t, err)
+}
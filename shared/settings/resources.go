@@ -0,0 +1,85 @@
+package settings
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/argoproj-labs/argocd-notifications/expr"
+	"github.com/argoproj-labs/argocd-notifications/shared/argocd"
+	"github.com/argoproj/notifications-engine/pkg/api"
+	"github.com/ghodss/yaml"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	resourcesKey = "resources"
+)
+
+// CustomResource holds settings of an additional resource the controller sends notifications about
+type CustomResource struct {
+	Group    string `json:"group,omitempty"`
+	Version  string `json:"version"`
+	Resource string `json:"resource"`
+	// VarName is the name of the variable which holds the resource in templates and triggers. Defaults to the resource name.
+	VarName       string `json:"varName,omitempty"`
+	LabelSelector string `json:"labelSelector,omitempty"`
+}
+
+func (r CustomResource) GroupVersionResource() schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: r.Group, Version: r.Version, Resource: r.Resource}
+}
+
+func (r CustomResource) GetVarName() string {
+	if r.VarName != "" {
+		return r.VarName
+	}
+	return r.Resource
+}
+
+// ParseCustomResources returns custom resources listed in the "resources" key of the notifications ConfigMap
+func ParseCustomResources(configMap *v1.ConfigMap) ([]CustomResource, error) {
+	resourcesYaml, ok := configMap.Data[resourcesKey]
+	if !ok {
+		return nil, nil
+	}
+	var resources []CustomResource
+	if err := yaml.Unmarshal([]byte(resourcesYaml), &resources); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %v", resourcesKey, err)
+	}
+	for _, r := range resources {
+		if r.Version == "" || r.Resource == "" {
+			return nil, fmt.Errorf("resource %s must have version and resource name", r.GroupVersionResource())
+		}
+		if reserved := getReservedVarNames(); reserved[r.GetVarName()] {
+			return nil, fmt.Errorf("resource %s cannot use reserved variable name '%s', reserved names are: %s",
+				r.GroupVersionResource(), r.GetVarName(), strings.Join(sortedNames(reserved), ", "))
+		}
+	}
+	return resources, nil
+}
+
+// getReservedVarNames returns names of variables available in templates and triggers of all resources: the
+// context, the destination variables and expression helpers
+func getReservedVarNames() map[string]bool {
+	res := map[string]bool{"context": true, "serviceType": true, "recipient": true}
+	for _, name := range expr.Namespaces() {
+		res[name] = true
+	}
+	return res
+}
+
+func sortedNames(m map[string]bool) []string {
+	var res []string
+	for k := range m {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// GetCustomResourceFactorySettings returns settings of the API that exposes the custom resource under its variable name
+func GetCustomResourceFactorySettings(argocdService argocd.Service, resource CustomResource, getContext ContextFunc) api.Settings {
+	return getFactorySettings(argocdService, resource.GetVarName(), getContext)
+}
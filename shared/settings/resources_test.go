@@ -0,0 +1,62 @@
+package settings
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestParseCustomResources(t *testing.T) {
+	resources, err := ParseCustomResources(&v1.ConfigMap{Data: map[string]string{
+		"resources": `
+- group: argoproj.io
+  version: v1alpha1
+  resource: rollouts
+  varName: rollout
+- group: argoproj.io
+  version: v1alpha1
+  resource: workflows
+`,
+	}})
+
+	assert.NoError(t, err)
+	assert.Len(t, resources, 2)
+	assert.Equal(t, "rollout", resources[0].GetVarName())
+	assert.Equal(t, "workflows", resources[1].GetVarName())
+	assert.Equal(t, "argoproj.io/v1alpha1, Resource=rollouts", resources[0].GroupVersionResource().String())
+}
+
+func TestParseCustomResources_Invalid(t *testing.T) {
+	_, err := ParseCustomResources(&v1.ConfigMap{Data: map[string]string{
+		"resources": `
+- group: argoproj.io
+  resource: rollouts
+`,
+	}})
+
+	assert.Error(t, err)
+}
+
+func TestParseCustomResources_NotConfigured(t *testing.T) {
+	resources, err := ParseCustomResources(&v1.ConfigMap{})
+
+	assert.NoError(t, err)
+	assert.Empty(t, resources)
+}
+
+func TestParseCustomResources_ReservedVarName(t *testing.T) {
+	for _, varName := range []string{"context", "time", "repo", "strings", "recipient", "serviceType"} {
+		_, err := ParseCustomResources(&v1.ConfigMap{Data: map[string]string{
+			"resources": `
+- group: argoproj.io
+  version: v1alpha1
+  resource: rollouts
+  varName: ` + varName,
+		}})
+
+		if assert.Error(t, err, varName) {
+			assert.Contains(t, err.Error(), "reserved variable name '"+varName+"'")
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package settings
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	retryKey       = "retry"
+	retryKeyPrefix = retryKey + "."
+)
+
+// RetryPolicy configures retries of failed notification deliveries
+type RetryPolicy struct {
+	// MaxAttempts is the total number of delivery attempts including the first one
+	MaxAttempts  int             `json:"maxAttempts,omitempty"`
+	InitialDelay metav1.Duration `json:"initialDelay,omitempty"`
+	MaxDelay     metav1.Duration `json:"maxDelay,omitempty"`
+	Factor       float64         `json:"factor,omitempty"`
+}
+
+// DefaultRetryPolicy is used for services without a retry policy in the notifications ConfigMap: failed deliveries are
+// not retried unless retries are configured.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:  1,
+	InitialDelay: metav1.Duration{Duration: time.Second},
+	MaxDelay:     metav1.Duration{Duration: 30 * time.Second},
+	Factor:       2,
+}
+
+// Delay returns the duration to wait after the given failed attempt
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	delay := time.Duration(float64(p.InitialDelay.Duration) * math.Pow(p.Factor, float64(attempt-1)))
+	if delay > p.MaxDelay.Duration || delay < 0 {
+		return p.MaxDelay.Duration
+	}
+	return delay
+}
+
+func (p RetryPolicy) validate() error {
+	if p.MaxAttempts < 1 {
+		return fmt.Errorf("maxAttempts must be positive")
+	}
+	if p.Factor < 1 {
+		return fmt.Errorf("factor must not be less than 1")
+	}
+	if p.InitialDelay.Duration < 0 || p.MaxDelay.Duration < p.InitialDelay.Duration {
+		return fmt.Errorf("maxDelay must not be less than initialDelay")
+	}
+	return nil
+}
+
+// RetryPolicies holds retry policies of notification services
+type RetryPolicies struct {
+	Default  RetryPolicy
+	Services map[string]RetryPolicy
+}
+
+// Get returns the retry policy of the service with the given name
+func (p RetryPolicies) Get(service string) RetryPolicy {
+	if policy, ok := p.Services[service]; ok {
+		return policy
+	}
+	return p.Default
+}
+
+// ParseRetryPolicies returns retry policies configured in the notifications ConfigMap: the "retry" key overrides the
+// default policy of all services and the "retry.<service-name>" keys override the policy of the specific service.
+func ParseRetryPolicies(configMap *v1.ConfigMap) (RetryPolicies, error) {
+	policies := RetryPolicies{Default: DefaultRetryPolicy, Services: map[string]RetryPolicy{}}
+	if policyYaml, ok := configMap.Data[retryKey]; ok {
+		if err := unmarshalRetryPolicy(retryKey, policyYaml, &policies.Default); err != nil {
+			return policies, err
+		}
+	}
+	for k, v := range configMap.Data {
+		if !strings.HasPrefix(k, retryKeyPrefix) {
+			continue
+		}
+		policy := policies.Default
+		if err := unmarshalRetryPolicy(k, v, &policy); err != nil {
+			return policies, err
+		}
+		policies.Services[strings.TrimPrefix(k, retryKeyPrefix)] = policy
+	}
+	return policies, nil
+}
+
+func unmarshalRetryPolicy(key string, policyYaml string, policy *RetryPolicy) error {
+	if err := yaml.Unmarshal([]byte(policyYaml), policy); err != nil {
+		return fmt.Errorf("failed to unmarshal %s: %v", key, err)
+	}
+	if err := policy.validate(); err != nil {
+		return fmt.Errorf("invalid %s: %v", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package settings
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestParseRetryPolicies(t *testing.T) {
+	policies, err := ParseRetryPolicies(&v1.ConfigMap{Data: map[string]string{
+		"retry": `
+maxAttempts: 5
+`,
+		"retry.slack": `
+initialDelay: 2s
+maxDelay: 1m
+`,
+	}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5, policies.Get("webhook").MaxAttempts)
+	assert.Equal(t, time.Second, policies.Get("webhook").InitialDelay.Duration)
+	slack := policies.Get("slack")
+	assert.Equal(t, 5, slack.MaxAttempts)
+	assert.Equal(t, 2*time.Second, slack.InitialDelay.Duration)
+	assert.Equal(t, time.Minute, slack.MaxDelay.Duration)
+}
+
+func TestParseRetryPolicies_NotConfigured(t *testing.T) {
+	policies, err := ParseRetryPolicies(&v1.ConfigMap{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultRetryPolicy, policies.Get("slack"))
+	assert.Equal(t, 1, policies.Get("slack").MaxAttempts)
+}
+
+func TestParseRetryPolicies_Invalid(t *testing.T) {
+	_, err := ParseRetryPolicies(&v1.ConfigMap{Data: map[string]string{
+		"retry.slack": `
+maxAttempts: 0
+`,
+	}})
+
+	assert.Error(t, err)
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	policy := DefaultRetryPolicy
+	assert.Equal(t, time.Second, policy.Delay(1))
+	assert.Equal(t, 2*time.Second, policy.Delay(2))
+	assert.Equal(t, 4*time.Second, policy.Delay(3))
+	assert.Equal(t, 30*time.Second, policy.Delay(10))
+}
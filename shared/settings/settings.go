@@ -1,6 +1,8 @@
 package settings
 
 import (
+	"context"
+
 	"github.com/argoproj-labs/argocd-notifications/expr"
 	"github.com/argoproj-labs/argocd-notifications/shared/argocd"
 	"github.com/argoproj-labs/argocd-notifications/shared/k8s"
@@ -11,17 +13,37 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
-func GetFactorySettings(argocdService argocd.Service) api.Settings {
+func GetFactorySettings(argocdService argocd.Service, getContext ContextFunc) api.Settings {
+	return getFactorySettings(argocdService, "app", getContext)
+}
+
+// GetAppSetFactorySettings returns settings of the API that exposes ApplicationSet as the "appset" variable
+func GetAppSetFactorySettings(argocdService argocd.Service, getContext ContextFunc) api.Settings {
+	return getFactorySettings(argocdService, "appset", getContext)
+}
+
+// ContextFunc returns the context used by expression helpers while the variables of the given resource are
+// evaluated, e.g. to trace calls of Argo CD as a part of the resource processing
+type ContextFunc func(obj map[string]interface{}) context.Context
+
+func (f ContextFunc) get(obj map[string]interface{}) context.Context {
+	if f == nil {
+		return context.Background()
+	}
+	return f(obj)
+}
+
+func getFactorySettings(argocdService argocd.Service, varName string, getContext ContextFunc) api.Settings {
 	return api.Settings{
 		SecretName:    k8s.SecretName,
 		ConfigMapName: k8s.ConfigMapName,
 		InitGetVars: func(cfg *api.Config, configMap *v1.ConfigMap, secret *v1.Secret) (api.GetVars, error) {
-			return initGetVars(argocdService, cfg, configMap, secret)
+			return initGetVars(argocdService, varName, getContext, cfg, configMap, secret)
 		},
 	}
 }
 
-func initGetVars(argocdService argocd.Service, cfg *api.Config, configMap *v1.ConfigMap, secret *v1.Secret) (api.GetVars, error) {
+func initGetVars(argocdService argocd.Service, varName string, getContext ContextFunc, cfg *api.Config, configMap *v1.ConfigMap, secret *v1.Secret) (api.GetVars, error) {
 	context := map[string]string{}
 	if contextYaml, ok := configMap.Data["context"]; ok {
 		if err := yaml.Unmarshal([]byte(contextYaml), &context); err != nil {
@@ -31,10 +53,14 @@ func initGetVars(argocdService argocd.Service, cfg *api.Config, configMap *v1.Co
 	if err := ApplyLegacyConfig(cfg, context, configMap, secret); err != nil {
 		return nil, err
 	}
+	gitProviders, err := ParseGitProviders(configMap)
+	if err != nil {
+		return nil, err
+	}
 
 	return func(obj map[string]interface{}, dest services.Destination) map[string]interface{} {
-		return expr.Spawn(&unstructured.Unstructured{Object: obj}, argocdService, map[string]interface{}{
-			"app":     obj,
+		return expr.Spawn(getContext.get(obj), &unstructured.Unstructured{Object: obj}, argocdService, gitProviders, map[string]interface{}{
+			varName:   obj,
 			"context": injectLegacyVar(context, dest.Service),
 		})
 	}, nil
@@ -0,0 +1,111 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// StdoutFile is the file name which makes the file exporter write spans to the standard output
+	StdoutFile = "-"
+)
+
+// Options configures span exporters. Tracing is disabled if no exporter is configured.
+type Options struct {
+	// OTLPAddress is the address of the OTLP gRPC collector
+	OTLPAddress  string
+	OTLPInsecure bool
+	OTLPHeaders  map[string]string
+	// File is the path of the file which spans are written to as JSON, intended for local testing
+	File string
+	// SampleRatio is the fraction of traces which are sampled
+	SampleRatio float64
+}
+
+func (o Options) enabled() bool {
+	return o.OTLPAddress != "" || o.File != ""
+}
+
+// Init configures the global tracer provider according to the options and returns the function which flushes
+// buffered spans and stops exporters
+func Init(ctx context.Context, serviceName string, opts Options) (func(ctx context.Context) error, error) {
+	if !opts.enabled() {
+		return func(ctx context.Context) error { return nil }, nil
+	}
+	if opts.SampleRatio < 0 || opts.SampleRatio > 1 {
+		return nil, errors.New("trace sample ratio must be between 0 and 1")
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(serviceName)))
+	if err != nil {
+		return nil, err
+	}
+	providerOpts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	}
+	var closers []io.Closer
+	if opts.OTLPAddress != "" {
+		clientOpts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(opts.OTLPAddress), otlptracegrpc.WithHeaders(opts.OTLPHeaders)}
+		if opts.OTLPInsecure {
+			clientOpts = append(clientOpts, otlptracegrpc.WithInsecure())
+		}
+		exporter, err := otlptracegrpc.New(ctx, clientOpts...)
+		if err != nil {
+			return nil, err
+		}
+		providerOpts = append(providerOpts, sdktrace.WithBatcher(exporter))
+		log.Infof("Exporting traces to OTLP collector %s", opts.OTLPAddress)
+	}
+	if opts.File != "" {
+		var w io.Writer = os.Stdout
+		if opts.File != StdoutFile {
+			f, err := os.OpenFile(opts.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+			if err != nil {
+				return nil, err
+			}
+			closers = append(closers, f)
+			w = f
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(w))
+		if err != nil {
+			return nil, err
+		}
+		providerOpts = append(providerOpts, sdktrace.WithBatcher(exporter))
+		log.Infof("Writing traces to %s", opts.File)
+	}
+
+	provider := sdktrace.NewTracerProvider(providerOpts...)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		for _, c := range closers {
+			if closeErr := c.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		return err
+	}, nil
+}
+
+// End records the error of the operation, if any, and ends the span
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -11,8 +11,9 @@ import (
 
 func NewFakeClient(objects ...runtime.Object) *fake.FakeDynamicClient {
 	return fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
-		schema.GroupVersionResource{Group: "argoproj.io", Resource: "applications", Version: "v1alpha1"}: "List",
-		schema.GroupVersionResource{Group: "argoproj.io", Resource: "appprojects", Version: "v1alpha1"}:  "List",
+		schema.GroupVersionResource{Group: "argoproj.io", Resource: "applications", Version: "v1alpha1"}:    "List",
+		schema.GroupVersionResource{Group: "argoproj.io", Resource: "appprojects", Version: "v1alpha1"}:     "List",
+		schema.GroupVersionResource{Group: "argoproj.io", Resource: "applicationsets", Version: "v1alpha1"}: "List",
 	}, objects...)
 }
 
@@ -3,6 +3,7 @@ package testing
 import (
 	"time"
 
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
@@ -17,6 +18,28 @@ func WithAnnotations(annotations map[string]string) func(obj *unstructured.Unstr
 	}
 }
 
+func WithNamespace(namespace string) func(obj *unstructured.Unstructured) {
+	return func(obj *unstructured.Unstructured) {
+		obj.SetNamespace(namespace)
+	}
+}
+
+func WithSourceNamespaces(namespaces ...string) func(proj *unstructured.Unstructured) {
+	return func(proj *unstructured.Unstructured) {
+		_ = unstructured.SetNestedStringSlice(proj.Object, namespaces, "spec", "sourceNamespaces")
+	}
+}
+
+func WithSyncWindows(windows ...map[string]interface{}) func(proj *unstructured.Unstructured) {
+	return func(proj *unstructured.Unstructured) {
+		var items []interface{}
+		for i := range windows {
+			items = append(items, windows[i])
+		}
+		_ = unstructured.SetNestedSlice(proj.Object, items, "spec", "syncWindows")
+	}
+}
+
 func WithProject(project string) func(app *unstructured.Unstructured) {
 	return func(app *unstructured.Unstructured) {
 		_ = unstructured.SetNestedField(app.Object, project, "spec", "project")
@@ -90,6 +113,12 @@ func WithRepoURL(repo string) func(app *unstructured.Unstructured) {
 	}
 }
 
+func WithDestinationNamespace(namespace string) func(app *unstructured.Unstructured) {
+	return func(app *unstructured.Unstructured) {
+		_ = unstructured.SetNestedField(app.Object, namespace, "spec", "destination", "namespace")
+	}
+}
+
 func NewApp(name string, modifiers ...func(app *unstructured.Unstructured)) *unstructured.Unstructured {
 	app := unstructured.Unstructured{}
 	app.SetGroupVersionKind(schema.GroupVersionKind{Group: "argoproj.io", Kind: "application", Version: "v1alpha1"})
@@ -101,6 +130,27 @@ func NewApp(name string, modifiers ...func(app *unstructured.Unstructured)) *uns
 	return &app
 }
 
+func WithOwner(kind string, name string) func(obj *unstructured.Unstructured) {
+	return func(obj *unstructured.Unstructured) {
+		obj.SetOwnerReferences(append(obj.GetOwnerReferences(), v1.OwnerReference{
+			APIVersion: "argoproj.io/v1alpha1",
+			Kind:       kind,
+			Name:       name,
+		}))
+	}
+}
+
+func NewAppSet(name string, modifiers ...func(appSet *unstructured.Unstructured)) *unstructured.Unstructured {
+	appSet := unstructured.Unstructured{}
+	appSet.SetGroupVersionKind(schema.GroupVersionKind{Group: "argoproj.io", Kind: "applicationset", Version: "v1alpha1"})
+	appSet.SetName(name)
+	appSet.SetNamespace(TestNamespace)
+	for i := range modifiers {
+		modifiers[i](&appSet)
+	}
+	return &appSet
+}
+
 func NewProject(name string, modifiers ...func(app *unstructured.Unstructured)) *unstructured.Unstructured {
 	proj := unstructured.Unstructured{}
 	proj.SetGroupVersionKind(schema.GroupVersionKind{Group: "argoproj.io", Kind: "appproject", Version: "v1alpha1"})